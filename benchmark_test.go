@@ -1,6 +1,8 @@
 package kset_test
 
 import (
+	"bytes"
+	"math/rand/v2"
 	"testing"
 
 	"github.com/sonalys/kset"
@@ -33,3 +35,201 @@ func BenchmarkHashMapKey_Difference_1000(b *testing.B) {
 		set.Difference(kset.HashMapKey(1))
 	}
 }
+
+// unorderedKeySet hides the ordering of a set, forcing set operations to probe keys one by one.
+type unorderedKeySet[Key any] struct {
+	kset.KeySet[Key]
+}
+
+func BenchmarkTreeMapKey_Algebra_1M(b *testing.B) {
+	const size = 1_000_000
+
+	evens := make([]int, 0, size)
+	thirds := make([]int, 0, size)
+	for i := range size {
+		evens = append(evens, i*2)
+		thirds = append(thirds, i*3)
+	}
+
+	set := kset.TreeMapKey(evens...)
+	other := kset.TreeMapKey(thirds...)
+
+	operations := []struct {
+		name string
+		f    func(other kset.ReadOnlyKeySet[int]) kset.KeySet[int]
+	}{
+		{name: "Union", f: set.Union},
+		{name: "Intersect", f: func(other kset.ReadOnlyKeySet[int]) kset.KeySet[int] { return set.Intersect(other) }},
+		{name: "Difference", f: func(other kset.ReadOnlyKeySet[int]) kset.KeySet[int] { return set.Difference(other) }},
+		{name: "SymmetricDifference", f: set.SymmetricDifference},
+	}
+
+	for _, op := range operations {
+		b.Run(op.name+"/merge", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				op.f(other)
+			}
+		})
+
+		b.Run(op.name+"/probe", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				op.f(unorderedKeySet[int]{other})
+			}
+		})
+	}
+}
+
+func BenchmarkContainsKeys_1M(b *testing.B) {
+	const size = 1_000_000
+
+	data := setupData(size)
+
+	sets := []struct {
+		name string
+		set  kset.KeySet[int]
+	}{
+		{name: "HashMapKey", set: kset.HashMapKey(data...)},
+		{name: "TreeMapKey", set: kset.TreeMapKey(data...)},
+		{name: "SortedSliceKey", set: kset.SortedSliceKey(data...)},
+	}
+
+	for _, tc := range sets {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tc.set.ContainsKeys(i % size)
+			}
+		})
+	}
+}
+
+func BenchmarkSortedSliceKey_Append_1M(b *testing.B) {
+	data := setupData(1_000_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kset.SortedSliceKey[int]().Append(data...)
+	}
+}
+
+func BenchmarkBitSetKey_Union_1M(b *testing.B) {
+	data := setupData(1_000_000)
+
+	runBenchmark := func(constructor func(keys ...int) kset.KeySet[int]) func(b *testing.B) {
+		set := constructor(data[:len(data)/2]...)
+		other := constructor(data[len(data)/4:]...)
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				set.Union(other)
+			}
+		}
+	}
+
+	b.Run("BitSetKey", runBenchmark(kset.BitSetKey[int]))
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey[int]))
+}
+
+func BenchmarkRoaringKey_Union_1M(b *testing.B) {
+	data := make([]uint32, 1_000_000)
+	for i := range data {
+		data[i] = rand.Uint32()
+	}
+
+	runBenchmark := func(constructor func(keys ...uint32) kset.KeySet[uint32]) func(b *testing.B) {
+		set := constructor(data[:len(data)/2]...)
+		other := constructor(data[len(data)/4:]...)
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				set.Union(other)
+			}
+		}
+	}
+
+	b.Run("RoaringKey", runBenchmark(kset.RoaringKey[uint32]))
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey[uint32]))
+}
+
+// BenchmarkAppend_Parallel measures lock contention between goroutines appending to the same set.
+// Run with -cpu 1,4,16 to observe how each implementation scales with the number of cores.
+func BenchmarkAppend_Parallel(b *testing.B) {
+	runBenchmark := func(set kset.KeySet[uint64]) func(b *testing.B) {
+		return func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				for pb.Next() {
+					set.Append(rng.Uint64N(1 << 20))
+				}
+			})
+		}
+	}
+
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey[uint64]()))
+	b.Run("ShardedHashMapKey", runBenchmark(kset.ShardedHashMapKey[uint64](0)))
+}
+
+// BenchmarkMixed_Parallel measures a read-mostly workload, with one write every 10 operations.
+func BenchmarkMixed_Parallel(b *testing.B) {
+	runBenchmark := func(set kset.KeySet[uint64]) func(b *testing.B) {
+		return func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				for pb.Next() {
+					key := rng.Uint64N(1 << 20)
+					if key%10 == 0 {
+						set.Append(key)
+					} else {
+						set.ContainsKeys(key)
+					}
+				}
+			})
+		}
+	}
+
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey[uint64]()))
+	b.Run("ShardedHashMapKey", runBenchmark(kset.ShardedHashMapKey[uint64](0)))
+}
+
+// BenchmarkClone_1M compares copying a set against sharing the nodes of a persistent one.
+func BenchmarkClone_1M(b *testing.B) {
+	data := setupData(1_000_000)
+
+	runBenchmark := func(set kset.KeySet[int]) func(b *testing.B) {
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				clone := set.Clone()
+				clone.Append(i)
+			}
+		}
+	}
+
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey(data...)))
+	b.Run("TreeMapKey", runBenchmark(kset.TreeMapKey(data...)))
+	b.Run("PersistentHashMapKey", runBenchmark(kset.PersistentHashMapKey(data...)))
+	b.Run("PersistentTreeMapKey", runBenchmark(kset.PersistentTreeMapKey(data...)))
+}
+
+// BenchmarkStream_1M measures a snapshot and restore round trip through WriteTo and ReadFrom.
+func BenchmarkStream_1M(b *testing.B) {
+	data := setupData(1_000_000)
+
+	runBenchmark := func(constructor func(keys ...int) kset.KeySet[int]) func(b *testing.B) {
+		return func(b *testing.B) {
+			set := constructor(data...)
+			restored := constructor()
+			var buffer bytes.Buffer
+
+			for i := 0; i < b.N; i++ {
+				buffer.Reset()
+				if _, err := set.WriteTo(&buffer); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := restored.ReadFrom(&buffer); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("HashMapKey", runBenchmark(kset.HashMapKey[int]))
+	b.Run("TreeMapKey", runBenchmark(func(keys ...int) kset.KeySet[int] { return kset.TreeMapKey(keys...) }))
+	b.Run("SortedSliceKey", runBenchmark(func(keys ...int) kset.KeySet[int] { return kset.SortedSliceKey(keys...) }))
+}
@@ -0,0 +1,396 @@
+package kset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// binaryVersion is the version of the binary format, written as its first byte.
+// Decoders reject versions they do not know.
+const binaryVersion = 1
+
+// Element encodings of the binary format.
+// The encoding is written once, followed by the number of elements, followed by the elements.
+const (
+	// binaryCodec elements are encoded by a Codec, each prefixed by its length as an uvarint.
+	binaryCodec byte = iota
+	// binaryVarint elements are signed integers, encoded as zigzag varints.
+	binaryVarint
+	// binaryUvarint elements are unsigned integers, encoded as uvarints.
+	binaryUvarint
+	// binaryString elements are strings, each prefixed by its length as an uvarint.
+	binaryString
+	// binaryGob elements are encoded together as a single gob slice, prefixed by its length as an uvarint.
+	// It avoids sending the gob type information once per element.
+	binaryGob
+
+	// binaryDelta is set on integer encodings when the elements are strictly ascending, as iterated from ordered sets.
+	// Only the first element is encoded in full, the following ones are encoded as the uvarint difference to the previous one.
+	binaryDelta byte = 0x80
+)
+
+// ErrInvalidBinary is returned when decoding data that is not a valid binary encoding of a set.
+var ErrInvalidBinary = errors.New("kset: invalid binary encoding")
+
+// Codec encodes and decodes elements of a set to and from binary.
+// Each encoded element is prefixed by its length, so Decode receives exactly the bytes appended by Append.
+type Codec[T any] interface {
+	// Append appends the encoding of the value to data, returning the extended slice.
+	Append(data []byte, value T) ([]byte, error)
+	// Decode decodes a value from the bytes appended by Append.
+	Decode(data []byte) (T, error)
+}
+
+// GobCodec returns a Codec encoding each element with encoding/gob.
+// Each element carries its own type information, so it is larger and slower than
+// the gob encoding used by MarshalBinary, which encodes all elements together.
+func GobCodec[T any]() Codec[T] {
+	return gobCodec[T]{}
+}
+
+// JSONCodec returns a Codec encoding each element with encoding/json.
+func JSONCodec[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+type gobCodec[T any] struct{}
+
+func (gobCodec[T]) Append(data []byte, value T) ([]byte, error) {
+	buffer := bytes.NewBuffer(data)
+	err := gob.NewEncoder(buffer).Encode(value)
+	return buffer.Bytes(), err
+}
+
+func (gobCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Append(data []byte, value T) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	return append(data, encoded...), err
+}
+
+func (jsonCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// MarshalBinaryWith encodes the values of the set, encoding each one with the given codec.
+// The result can be decoded with UnmarshalBinaryWith and the same codec.
+// Example:
+//
+//	data, err := kset.MarshalBinaryWith(users, kset.JSONCodec[User]())
+func MarshalBinaryWith[Key comparable, Value any](set ReadOnlyKeyValueSet[Key, Value], codec Codec[Value]) ([]byte, error) {
+	return appendBinary(nil, set.Slice(), codec)
+}
+
+// UnmarshalBinaryWith replaces the elements of the set with values encoded by MarshalBinaryWith, decoding each one with the given codec.
+// Keys are derived from the decoded values through the selector of the set. It follows the decoding rules of Set.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID })
+//	err := kset.UnmarshalBinaryWith(users, data, kset.JSONCodec[User]())
+func UnmarshalBinaryWith[Key comparable, Value any](set KeyValueSet[Key, Value], data []byte, codec Codec[Value]) error {
+	values, err := decodeBinary(data, codec)
+	if err != nil {
+		return err
+	}
+	return replaceValues(set, func(add func([]Value) error) error {
+		return add(values)
+	})
+}
+
+func (k *keySet[Key, Store]) MarshalBinary() ([]byte, error) {
+	return appendBinary[Key](nil, k.Slice(), nil)
+}
+
+func (k *keySet[Key, Store]) UnmarshalBinary(data []byte) error {
+	keys, err := decodeBinary[Key](data, nil)
+	if err != nil {
+		return err
+	}
+	return replaceKeys(k, func(add func([]Key) error) error {
+		return add(keys)
+	})
+}
+
+func (k *keySet[Key, Store]) GobEncode() ([]byte, error) {
+	return k.MarshalBinary()
+}
+
+func (k *keySet[Key, Store]) GobDecode(data []byte) error {
+	return k.UnmarshalBinary(data)
+}
+
+func (k *keyValueSet[Key, Value, Store]) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryWith(k, nil)
+}
+
+func (k *keyValueSet[Key, Value, Store]) UnmarshalBinary(data []byte) error {
+	return UnmarshalBinaryWith(k, data, nil)
+}
+
+func (k *keyValueSet[Key, Value, Store]) GobEncode() ([]byte, error) {
+	return k.MarshalBinary()
+}
+
+func (k *keyValueSet[Key, Value, Store]) GobDecode(data []byte) error {
+	return k.UnmarshalBinary(data)
+}
+
+// appendBinary appends the versioned encoding of the elements to data.
+// A nil codec encodes integers and strings natively, and other types with encoding/gob.
+func appendBinary[T any](data []byte, elements []T, codec Codec[T]) ([]byte, error) {
+	data = append(data, binaryVersion)
+	return appendElements(data, elements, codec)
+}
+
+// decodeBinary decodes the elements appended by appendBinary.
+func decodeBinary[T any](data []byte, codec Codec[T]) ([]T, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidBinary
+	}
+	if data[0] != binaryVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBinary, data[0])
+	}
+
+	var elements []T
+	rest, err := decodeElements(data[1:], codec, func(count int) {
+		elements = make([]T, 0, count)
+	}, func(element T) {
+		elements = append(elements, element)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidBinary, len(rest))
+	}
+	return elements, nil
+}
+
+// appendElements appends the encoding, the number of elements and the encoded elements to data.
+func appendElements[T any](data []byte, elements []T, codec Codec[T]) ([]byte, error) {
+	values := reflect.ValueOf(elements)
+
+	switch kind := reflect.TypeFor[T]().Kind(); {
+	case codec != nil:
+	case isSigned(kind):
+		return appendIntegers(data, binaryVarint, len(elements), func(i int) uint64 {
+			return uint64(values.Index(i).Int())
+		}), nil
+	case isUnsigned(kind):
+		return appendIntegers(data, binaryUvarint, len(elements), func(i int) uint64 {
+			return values.Index(i).Uint()
+		}), nil
+	case kind == reflect.String:
+		data = append(data, binaryString)
+		data = binary.AppendUvarint(data, uint64(len(elements)))
+		for i := range elements {
+			element := values.Index(i).String()
+			data = binary.AppendUvarint(data, uint64(len(element)))
+			data = append(data, element...)
+		}
+		return data, nil
+	default:
+		data = append(data, binaryGob)
+		data = binary.AppendUvarint(data, uint64(len(elements)))
+		if len(elements) == 0 {
+			return data, nil
+		}
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).Encode(elements); err != nil {
+			return nil, err
+		}
+		data = binary.AppendUvarint(data, uint64(buffer.Len()))
+		return append(data, buffer.Bytes()...), nil
+	}
+
+	data = append(data, binaryCodec)
+	data = binary.AppendUvarint(data, uint64(len(elements)))
+
+	var buffer []byte
+	for i := range elements {
+		var err error
+		if buffer, err = codec.Append(buffer[:0], elements[i]); err != nil {
+			return nil, err
+		}
+		data = binary.AppendUvarint(data, uint64(len(buffer)))
+		data = append(data, buffer...)
+	}
+	return data, nil
+}
+
+// appendIntegers appends integers given as their two's complement bits.
+// Strictly ascending integers are delta encoded.
+func appendIntegers(data []byte, encoding byte, count int, bits func(i int) uint64) []byte {
+	less := func(a, b uint64) bool { return a < b }
+	if encoding == binaryVarint {
+		less = func(a, b uint64) bool { return int64(a) < int64(b) }
+	}
+
+	ascending := count > 1
+	for i := 1; i < count && ascending; i++ {
+		ascending = less(bits(i-1), bits(i))
+	}
+	if ascending {
+		encoding |= binaryDelta
+	}
+
+	data = append(data, encoding)
+	data = binary.AppendUvarint(data, uint64(count))
+
+	var prev uint64
+	for i := range count {
+		current := bits(i)
+		switch {
+		case ascending && i > 0:
+			data = binary.AppendUvarint(data, current-prev)
+		case encoding&^binaryDelta == binaryVarint:
+			data = binary.AppendVarint(data, int64(current))
+		default:
+			data = binary.AppendUvarint(data, current)
+		}
+		prev = current
+	}
+	return data
+}
+
+// decodeElements decodes elements appended by appendElements, returning the remaining data.
+// grow is called with the number of elements before the first one is yielded.
+func decodeElements[T any](data []byte, codec Codec[T], grow func(count int), yield func(T)) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidBinary
+	}
+	encoding := data[0]
+
+	count, n := binary.Uvarint(data[1:])
+	// Every element takes at least one byte, which bounds the allocation for corrupted counts.
+	if n <= 0 || count > uint64(len(data)-1-n) {
+		return nil, ErrInvalidBinary
+	}
+	data = data[1+n:]
+	grow(int(count))
+
+	var element T
+	value := reflect.ValueOf(&element).Elem()
+
+	switch kind := value.Kind(); {
+	case encoding == binaryCodec:
+		if codec == nil {
+			return nil, fmt.Errorf("%w: elements encoded by a codec", ErrInvalidBinary)
+		}
+		for range count {
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return nil, ErrInvalidBinary
+			}
+			element, err := codec.Decode(data[n : n+int(size)])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidBinary, err)
+			}
+			yield(element)
+			data = data[n+int(size):]
+		}
+	case encoding&^binaryDelta == binaryVarint, encoding&^binaryDelta == binaryUvarint:
+		if codec != nil || !isSigned(kind) && !isUnsigned(kind) {
+			return nil, fmt.Errorf("%w: integers cannot be decoded into %s", ErrInvalidBinary, value.Type())
+		}
+		signed := encoding&^binaryDelta == binaryVarint
+		var prev uint64
+		for i := range count {
+			var bits uint64
+			switch {
+			case encoding&binaryDelta != 0 && i > 0:
+				bits, n = binary.Uvarint(data)
+				bits += prev
+			case signed:
+				var v int64
+				v, n = binary.Varint(data)
+				bits = uint64(v)
+			default:
+				bits, n = binary.Uvarint(data)
+			}
+			if n <= 0 || !setInteger(value, signed, bits) {
+				return nil, ErrInvalidBinary
+			}
+			yield(element)
+			data = data[n:]
+			prev = bits
+		}
+	case encoding == binaryString:
+		if codec != nil || kind != reflect.String {
+			return nil, fmt.Errorf("%w: strings cannot be decoded into %s", ErrInvalidBinary, value.Type())
+		}
+		for range count {
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return nil, ErrInvalidBinary
+			}
+			value.SetString(string(data[n : n+int(size)]))
+			yield(element)
+			data = data[n+int(size):]
+		}
+	case encoding == binaryGob:
+		if codec != nil {
+			return nil, fmt.Errorf("%w: elements encoded by gob", ErrInvalidBinary)
+		}
+		if count == 0 {
+			break
+		}
+		size, n := binary.Uvarint(data)
+		if n <= 0 || size > uint64(len(data)-n) {
+			return nil, ErrInvalidBinary
+		}
+		var elements []T
+		if err := gob.NewDecoder(bytes.NewReader(data[n : n+int(size)])).Decode(&elements); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBinary, err)
+		}
+		if uint64(len(elements)) != count {
+			return nil, ErrInvalidBinary
+		}
+		for _, element := range elements {
+			yield(element)
+		}
+		data = data[n+int(size):]
+	default:
+		return nil, fmt.Errorf("%w: unknown encoding %d", ErrInvalidBinary, encoding)
+	}
+
+	return data, nil
+}
+
+// setInteger sets the integer value to the given two's complement bits, reporting whether it fits.
+func setInteger(value reflect.Value, signed bool, bits uint64) bool {
+	if value.CanInt() {
+		if !signed && bits > math.MaxInt64 || value.OverflowInt(int64(bits)) {
+			return false
+		}
+		value.SetInt(int64(bits))
+		return true
+	}
+	if signed && int64(bits) < 0 || value.OverflowUint(bits) {
+		return false
+	}
+	value.SetUint(bits)
+	return true
+}
+
+func isSigned(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUnsigned(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
@@ -0,0 +1,175 @@
+package kset_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeySet_Binary(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
+		t.Run("round trip", func(t *testing.T) {
+			data, err := constructor(-5, 3, 1, math.MaxInt, math.MinInt).MarshalBinary()
+			require.NoError(t, err)
+
+			decoded := constructor(7)
+			require.NoError(t, decoded.UnmarshalBinary(data))
+			assert.ElementsMatch(t, []int{-5, 1, 3, math.MaxInt, math.MinInt}, decoded.Slice())
+		})
+
+		t.Run("empty", func(t *testing.T) {
+			data, err := constructor().MarshalBinary()
+			require.NoError(t, err)
+
+			decoded := constructor(1)
+			require.NoError(t, decoded.UnmarshalBinary(data))
+			assert.True(t, decoded.IsEmpty())
+		})
+
+		t.Run("gob", func(t *testing.T) {
+			var buffer bytes.Buffer
+			require.NoError(t, gob.NewEncoder(&buffer).Encode(constructor(1, 2, 3)))
+
+			decoded := constructor()
+			require.NoError(t, gob.NewDecoder(&buffer).Decode(decoded))
+			assert.ElementsMatch(t, []int{1, 2, 3}, decoded.Slice())
+		})
+
+		t.Run("frozen", func(t *testing.T) {
+			data, err := constructor(1, 2).Freeze().MarshalBinary()
+			require.NoError(t, err)
+
+			decoded := constructor()
+			require.NoError(t, decoded.UnmarshalBinary(data))
+			assert.ElementsMatch(t, []int{1, 2}, decoded.Slice())
+		})
+
+		t.Run("invalid data", func(t *testing.T) {
+			data, err := constructor(1, 2, 300).MarshalBinary()
+			require.NoError(t, err)
+
+			set := constructor(1)
+			for i := range len(data) - 1 {
+				require.ErrorIs(t, set.UnmarshalBinary(data[:i]), kset.ErrInvalidBinary, "truncated to %d bytes", i)
+			}
+			require.ErrorIs(t, set.UnmarshalBinary(append(data, 0)), kset.ErrInvalidBinary)
+			assert.Equal(t, []int{1}, set.Slice())
+		})
+	})
+
+	t.Run("delta encoding", func(t *testing.T) {
+		keys := make([]uint32, 0, 1000)
+		for i := range uint32(1000) {
+			keys = append(keys, 1_000_000+i*3)
+		}
+
+		ordered, err := kset.TreeMapKey(keys...).MarshalBinary()
+		require.NoError(t, err)
+		// Version, encoding and count, then the first key in full and one byte per delta.
+		assert.Len(t, ordered, 1+1+2+3+999)
+
+		decoded := kset.HashMapKey[uint32]()
+		require.NoError(t, decoded.UnmarshalBinary(ordered))
+		assert.ElementsMatch(t, keys, decoded.Slice())
+	})
+
+	t.Run("mismatched types", func(t *testing.T) {
+		data, err := kset.TreeMapKey(-1, 300).MarshalBinary()
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, kset.TreeMapKey[uint]().UnmarshalBinary(data), kset.ErrInvalidBinary)
+		assert.ErrorIs(t, kset.TreeMapKey[int8]().UnmarshalBinary(data), kset.ErrInvalidBinary)
+		assert.ErrorIs(t, kset.TreeMapKey[string]().UnmarshalBinary(data), kset.ErrInvalidBinary)
+
+		wide := kset.TreeMapKey[int64]()
+		require.NoError(t, wide.UnmarshalBinary(data))
+		assert.Equal(t, []int64{-1, 300}, wide.Slice())
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		err := kset.HashMapKey[int]().UnmarshalBinary([]byte{99, 0, 0})
+		assert.ErrorIs(t, err, kset.ErrInvalidBinary)
+	})
+
+	t.Run("strings", func(t *testing.T) {
+		data, err := kset.HashMapKey("a", "", "long string").MarshalBinary()
+		require.NoError(t, err)
+
+		decoded := kset.TreeMapKey[string]()
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		assert.Equal(t, []string{"", "a", "long string"}, decoded.Slice())
+	})
+
+	t.Run("other keys", func(t *testing.T) {
+		type point struct{ X, Y int }
+
+		data, err := kset.HashMapKey(point{1, 2}, point{3, 4}).MarshalBinary()
+		require.NoError(t, err)
+
+		decoded := kset.HashMapKey[point]()
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		assert.ElementsMatch(t, []point{{1, 2}, {3, 4}}, decoded.Slice())
+	})
+
+	t.Run("keys rejected by the storage", func(t *testing.T) {
+		data, err := kset.HashMapKey(4, -1).MarshalBinary()
+		require.NoError(t, err)
+
+		set := kset.BitSetKey(1, 2, 3)
+		require.ErrorIs(t, set.UnmarshalBinary(data), kset.ErrInvalidKey)
+		assert.Equal(t, []int{1, 2, 3}, set.Slice())
+	})
+}
+
+func Test_KeyValueSet_Binary(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	userID := func(u user) int { return u.ID }
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.KeyValueSet[int, user]) {
+		t.Run("round trip", func(t *testing.T) {
+			data, err := constructor(userID, user{ID: 2, Name: "Bob"}, user{ID: 1, Name: "Alice"}).MarshalBinary()
+			require.NoError(t, err)
+
+			decoded := constructor(userID, user{ID: 3})
+			require.NoError(t, decoded.UnmarshalBinary(data))
+			assert.Equal(t, map[int]user{1: {ID: 1, Name: "Alice"}, 2: {ID: 2, Name: "Bob"}}, decoded.Map())
+		})
+
+		t.Run("codec", func(t *testing.T) {
+			codec := kset.JSONCodec[user]()
+			data, err := kset.MarshalBinaryWith(constructor(userID, user{ID: 1, Name: "Alice"}), codec)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), `"Name":"Alice"`)
+
+			decoded := constructor(userID)
+			require.NoError(t, kset.UnmarshalBinaryWith(decoded, data, codec))
+			assert.Equal(t, map[int]user{1: {ID: 1, Name: "Alice"}}, decoded.Map())
+		})
+
+		t.Run("gob", func(t *testing.T) {
+			var buffer bytes.Buffer
+			require.NoError(t, gob.NewEncoder(&buffer).Encode(constructor(userID, user{ID: 1, Name: "Alice"})))
+
+			decoded := constructor(userID)
+			require.NoError(t, gob.NewDecoder(&buffer).Decode(decoded))
+			assert.Equal(t, map[int]user{1: {ID: 1, Name: "Alice"}}, decoded.Map())
+		})
+
+		t.Run("invalid data", func(t *testing.T) {
+			data, err := kset.MarshalBinaryWith(constructor(userID, user{ID: 2}), kset.JSONCodec[user]())
+			require.NoError(t, err)
+
+			set := constructor(userID, user{ID: 1})
+			require.ErrorIs(t, set.UnmarshalBinary(data), kset.ErrInvalidBinary)
+			assert.Equal(t, map[int]user{1: {ID: 1}}, set.Map())
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package kset
+
+// computeOp is the change applied by compute to the entry of a key.
+type computeOp uint8
+
+const (
+	// computeKeep leaves the entry unchanged.
+	computeKeep computeOp = iota
+	// computeUpsert stores the returned value for the key.
+	computeUpsert
+	// computeDelete removes the key, if present.
+	computeDelete
+)
+
+func (k *keyValueSet[Key, Value, Store]) Get(key Key) (Value, bool) {
+	return k.store.Get(key)
+}
+
+// GetOrInsert returns the value stored for the key of value, inserting value if the key is missing.
+func (k *keyValueSet[Key, Value, Store]) GetOrInsert(value Value) (Value, bool) {
+	key := k.selector(value)
+	// Hits only take the read lock.
+	if actual, ok := k.store.Get(key); ok {
+		return actual, true
+	}
+
+	loaded := false
+	actual, _ := k.compute(key, func(old Value, found bool) (Value, computeOp) {
+		if found {
+			loaded = true
+			return old, computeKeep
+		}
+		return value, computeUpsert
+	})
+	return actual, loaded
+}
+
+// Compute replaces the value stored for key with the result of fn, deleting the key if fn returns false.
+func (k *keyValueSet[Key, Value, Store]) Compute(key Key, fn func(old Value, ok bool) (Value, bool)) (Value, bool) {
+	return k.compute(key, func(old Value, found bool) (Value, computeOp) {
+		value, keep := fn(old, found)
+		if !keep {
+			return value, computeDelete
+		}
+		if k.selector(value) != key {
+			panic("kset: Compute must return a value with the same key")
+		}
+		return value, computeUpsert
+	})
+}
+
+// CompareAndSwap replaces old with new if the value stored for their key is equal to old according to eq.
+func (k *keyValueSet[Key, Value, Store]) CompareAndSwap(old, new Value, eq func(a, b Value) bool) bool {
+	key := k.selector(old)
+	if k.selector(new) != key {
+		panic("kset: CompareAndSwap values must have the same key")
+	}
+
+	swapped := false
+	k.compute(key, func(current Value, found bool) (Value, computeOp) {
+		if !found || !eq(current, old) {
+			return current, computeKeep
+		}
+		swapped = true
+		return new, computeUpsert
+	})
+	return swapped
+}
+
+// compute applies the change returned by fn to the entry of the key, reporting it to the observers of the set.
+func (k *keyValueSet[Key, Value, Store]) compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	if k.observers.active() {
+		return k.computeObserved(key, fn)
+	}
+	return computeStore[Key, Value](k.store, key, fn)
+}
+
+// computeStore applies the change returned by fn for the current entry of the key,
+// returning the value stored afterwards and whether the key is present.
+// It is atomic on computingStorage and lockingStorage, holding their locks while fn runs.
+func computeStore[Key, Value any](store Storage[Key, Value], key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	switch store := store.(type) {
+	case computingStorage[Key, Value]:
+		return store.compute(key, fn)
+	case lockingStorage[Key, Value]:
+		var value Value
+		var ok bool
+		store.locked(func(view Storage[Key, Value]) {
+			value, ok = computeStore(view, key, fn)
+		})
+		return value, ok
+	}
+
+	old, found := store.Get(key)
+	return applyCompute(old, found, fn,
+		func(value Value) { store.Upsert(key, value) },
+		func() { store.Delete(key) },
+	)
+}
+
+// applyCompute calls fn with the current entry of a key, and applies its change through upsert or remove.
+// remove is only called if the key was found.
+func applyCompute[Value any](old Value, found bool, fn func(old Value, found bool) (Value, computeOp), upsert func(Value), remove func()) (Value, bool) {
+	value, op := fn(old, found)
+	switch op {
+	case computeUpsert:
+		upsert(value)
+		return value, true
+	case computeDelete:
+		if found {
+			remove()
+		}
+		var zero Value
+		return zero, false
+	default:
+		return old, found
+	}
+}
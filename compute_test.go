@@ -0,0 +1,167 @@
+package kset_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	ID   int
+	Hits int
+}
+
+func counterID(c counter) int { return c.ID }
+
+func sameHits(a, b counter) bool { return a.Hits == b.Hits }
+
+func Test_KeyValueSet_Get(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10})
+
+		value, ok := set.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, counter{1, 10}, value)
+
+		value, ok = set.Freeze().Get(2)
+		assert.False(t, ok)
+		assert.Zero(t, value)
+	})
+}
+
+func Test_KeyValueSet_GetOrInsert(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10})
+
+		value, loaded := set.GetOrInsert(counter{1, 20})
+		assert.True(t, loaded)
+		assert.Equal(t, counter{1, 10}, value)
+
+		value, loaded = set.GetOrInsert(counter{2, 20})
+		assert.False(t, loaded)
+		assert.Equal(t, counter{2, 20}, value)
+		assert.Equal(t, map[int]counter{1: {1, 10}, 2: {2, 20}}, set.Map())
+	})
+}
+
+func Test_KeyValueSet_Compute(t *testing.T) {
+	increment := func(id int) func(old counter, ok bool) (counter, bool) {
+		return func(old counter, ok bool) (counter, bool) {
+			return counter{id, old.Hits + 1}, true
+		}
+	}
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		t.Run("upsert", func(t *testing.T) {
+			set := constructor(counterID, counter{1, 10})
+
+			value, ok := set.Compute(1, increment(1))
+			assert.True(t, ok)
+			assert.Equal(t, counter{1, 11}, value)
+
+			value, ok = set.Compute(2, increment(2))
+			assert.True(t, ok)
+			assert.Equal(t, counter{2, 1}, value)
+			assert.Equal(t, map[int]counter{1: {1, 11}, 2: {2, 1}}, set.Map())
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			set := constructor(counterID, counter{1, 10}, counter{2, 20})
+			remove := func(old counter, ok bool) (counter, bool) {
+				return old, false
+			}
+
+			value, ok := set.Compute(1, remove)
+			assert.False(t, ok)
+			assert.Zero(t, value)
+
+			_, ok = set.Compute(3, remove)
+			assert.False(t, ok)
+			assert.Equal(t, map[int]counter{2: {2, 20}}, set.Map())
+		})
+
+		t.Run("different key", func(t *testing.T) {
+			set := constructor(counterID, counter{1, 10})
+			assert.Panics(t, func() { set.Compute(1, increment(2)) })
+			assert.Equal(t, map[int]counter{1: {1, 10}}, set.Map())
+
+			// The set must remain usable after the panic.
+			set.Compute(1, increment(1))
+			assert.Equal(t, map[int]counter{1: {1, 11}}, set.Map())
+		})
+	})
+}
+
+func Test_KeyValueSet_CompareAndSwap(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10})
+
+		assert.True(t, set.CompareAndSwap(counter{1, 10}, counter{1, 11}, sameHits))
+		assert.False(t, set.CompareAndSwap(counter{1, 10}, counter{1, 12}, sameHits))
+		assert.False(t, set.CompareAndSwap(counter{2, 0}, counter{2, 1}, sameHits))
+		assert.Equal(t, map[int]counter{1: {1, 11}}, set.Map())
+
+		assert.Panics(t, func() { set.CompareAndSwap(counter{1, 11}, counter{2, 12}, sameHits) })
+		assert.Equal(t, map[int]counter{1: {1, 11}}, set.Map())
+	})
+}
+
+func Test_KeyValueSet_Compute_Concurrent(t *testing.T) {
+	const workers, increments = 8, 200
+
+	forEachThreadSafeStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID)
+
+		var mutex sync.Mutex
+		var observed []counter
+		var wg sync.WaitGroup
+		for worker := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range increments {
+					switch i % 3 {
+					case 0:
+						set.Compute(1, func(old counter, ok bool) (counter, bool) {
+							return counter{1, old.Hits + 1}, true
+						})
+					case 1:
+						// Retried until no other worker changed the counter in between.
+						for {
+							old, _ := set.GetOrInsert(counter{1, 0})
+							if set.CompareAndSwap(old, counter{1, old.Hits + 1}, sameHits) {
+								break
+							}
+						}
+					default:
+						actual, _ := set.GetOrInsert(counter{2 + worker%2, worker})
+						mutex.Lock()
+						observed = append(observed, actual)
+						mutex.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		expected := 0
+		for i := range increments {
+			if i%3 != 2 {
+				expected++
+			}
+		}
+		value, ok := set.Get(1)
+		require.True(t, ok)
+		assert.Equal(t, workers*expected, value.Hits, "no increment must be lost")
+		assert.Equal(t, 3, set.Len())
+
+		// Every worker must observe the value of the first insert of its key.
+		for _, actual := range observed {
+			value, _ := set.Get(actual.ID)
+			assert.Equal(t, value, actual)
+		}
+	})
+}
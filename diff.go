@@ -0,0 +1,62 @@
+package kset
+
+// Diff holds the differences between two key-value sets, from the first set to the second.
+type Diff[Key, Value any] struct {
+	// Added holds the values of the keys only present in the second set.
+	Added []Value
+	// Removed holds the values of the keys only present in the first set.
+	Removed []Value
+	// Changed holds the keys present in both sets with different values.
+	Changed []Change[Key, Value]
+}
+
+// Change is a key whose value differs between two sets.
+type Change[Key, Value any] struct {
+	Key Key
+	Old Value
+	New Value
+}
+
+// IsEmpty reports whether the sets have no differences.
+func (d Diff[Key, Value]) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (k *keyValueSet[Key, Value, Store]) EqualValues(other ReadOnlyKeyValueSet[Key, Value], eq func(a, b Value) bool) bool {
+	if k.Len() != other.Len() {
+		return false
+	}
+
+	// The sets are iterated through snapshots, so no lock is held while calling other and eq.
+	for key, value := range snapshotIter(k.store) {
+		otherValue, ok := other.Get(key)
+		if !ok || !eq(value, otherValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (k *keyValueSet[Key, Value, Store]) Diff(other ReadOnlyKeyValueSet[Key, Value], eq func(a, b Value) bool) Diff[Key, Value] {
+	var diff Diff[Key, Value]
+
+	// The sets are iterated through snapshots, so no lock is held while calling other and eq.
+	for key, value := range snapshotIter(k.store) {
+		otherValue, ok := other.Get(key)
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, value)
+		case !eq(value, otherValue):
+			diff.Changed = append(diff.Changed, Change[Key, Value]{Key: key, Old: value, New: otherValue})
+		}
+	}
+
+	for key, value := range other.KeyValues() {
+		if !k.store.Contains(key) {
+			diff.Added = append(diff.Added, value)
+		}
+	}
+
+	return diff
+}
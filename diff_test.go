@@ -0,0 +1,73 @@
+package kset_test
+
+import (
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+)
+
+func sameCounter(a, b counter) bool { return a == b }
+
+func Test_KeyValueSet_EqualValues(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 20})
+
+		assert.True(t, set.EqualValues(kset.HashMapKeyValue(counterID, counter{2, 20}, counter{1, 10}), sameCounter))
+		assert.True(t, set.EqualValues(set.Freeze(), sameCounter))
+
+		changed := kset.HashMapKeyValue(counterID, counter{1, 10}, counter{2, 21})
+		assert.True(t, set.Equal(changed))
+		assert.False(t, set.EqualValues(changed, sameCounter))
+		assert.True(t, set.EqualValues(changed, func(a, b counter) bool { return a.ID == b.ID }))
+
+		assert.False(t, set.EqualValues(kset.HashMapKeyValue(counterID, counter{1, 10}), sameCounter))
+		assert.False(t, set.EqualValues(kset.HashMapKeyValue(counterID, counter{1, 10}, counter{3, 20}), sameCounter))
+	})
+}
+
+func Test_KeyValueSet_Diff(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		t.Run("changes", func(t *testing.T) {
+			before := constructor(counterID, counter{1, 10}, counter{2, 20}, counter{3, 30})
+			after := kset.TreeMapKeyValue(counterID, counter{2, 21}, counter{3, 30}, counter{4, 40})
+
+			diff := before.Diff(after, sameCounter)
+			assert.False(t, diff.IsEmpty())
+			assert.Equal(t, []counter{{4, 40}}, diff.Added)
+			assert.Equal(t, []counter{{1, 10}}, diff.Removed)
+			assert.Equal(t, []kset.Change[int, counter]{{Key: 2, Old: counter{2, 20}, New: counter{2, 21}}}, diff.Changed)
+
+			reverse := after.Diff(before, sameCounter)
+			assert.Equal(t, diff.Added, reverse.Removed)
+			assert.Equal(t, diff.Removed, reverse.Added)
+			assert.Equal(t, []kset.Change[int, counter]{{Key: 2, Old: counter{2, 21}, New: counter{2, 20}}}, reverse.Changed)
+		})
+
+		t.Run("equal", func(t *testing.T) {
+			set := constructor(counterID, counter{1, 10}, counter{2, 20})
+			assert.True(t, set.Diff(set.Clone(), sameCounter).IsEmpty())
+			assert.True(t, constructor(counterID).Diff(kset.HashMapKeyValue(counterID), sameCounter).IsEmpty())
+		})
+
+		t.Run("eq accessing the sets", func(t *testing.T) {
+			// No lock is held while calling eq, so it can even write to the compared sets.
+			set := constructor(counterID, counter{1, 10}, counter{2, 20})
+			touch := func(a, b counter) bool {
+				set.Append(a)
+				return a == b
+			}
+			assert.True(t, set.Diff(set, touch).IsEmpty())
+			assert.True(t, set.EqualValues(set, touch))
+		})
+	})
+}
+
+func Test_OrderedKeyValueSet_Diff(t *testing.T) {
+	before := kset.TreeMapKeyValue(counterID, counter{5, 0}, counter{1, 0}, counter{3, 0})
+	after := kset.TreeMapKeyValue(counterID, counter{6, 0}, counter{2, 0}, counter{4, 0})
+
+	diff := before.Diff(after, sameCounter)
+	assert.Equal(t, []counter{{1, 0}, {3, 0}, {5, 0}}, diff.Removed)
+	assert.Equal(t, []counter{{2, 0}, {4, 0}, {6, 0}}, diff.Added)
+}
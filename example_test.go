@@ -3,6 +3,7 @@ package kset_test
 import (
 	"fmt"
 	"slices"
+	"time"
 
 	"github.com/sonalys/kset"
 )
@@ -76,3 +77,46 @@ func ExampleTreeMapKey() {
 	// Difference (setA - setB): [1 2]
 	// Symmetric Difference: [1 2 4 5]
 }
+
+func ExampleOrderedKeySet() {
+	timestamps := kset.TreeMapKey(30, 10, 50, 20, 40)
+
+	first, _ := timestamps.Min()
+	fmt.Printf("First: %d\n", first)
+
+	previous, _ := timestamps.Floor(35)
+	fmt.Printf("Floor(35): %d\n", previous)
+
+	fmt.Printf("Range [20, 40): %v\n", slices.Collect(timestamps.Range(20, 40)))
+	fmt.Printf("Backward: %v\n", slices.Collect(timestamps.Backward()))
+
+	// Output:
+	// First: 10
+	// Floor(35): 30
+	// Range [20, 40): [20 30]
+	// Backward: [50 40 30 20 10]
+}
+
+func ExampleTreeMapKeyValueFunc() {
+	type Event struct {
+		At   time.Time
+		Name string
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := kset.TreeMapKeyValueFunc(time.Time.Compare, func(e Event) time.Time { return e.At },
+		Event{At: base.Add(2 * time.Hour), Name: "deploy"},
+		Event{At: base, Name: "build"},
+		Event{At: base.Add(time.Hour), Name: "test"},
+	)
+
+	for _, event := range events.KeyValues() {
+		fmt.Println(event.Name)
+	}
+
+	// Output:
+	// build
+	// test
+	// deploy
+}
@@ -0,0 +1,61 @@
+package kset
+
+import (
+	"cmp"
+	"unsafe"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Exported constructors of the built-in storages, only available for tests.
+
+func NewSafeMapStore[Key comparable, Value any]() Storage[Key, Value] {
+	return &safeMapStore[Key, Value]{store: make(map[Key]Value)}
+}
+
+func NewUnsafeMapStore[Key comparable, Value any]() Storage[Key, Value] {
+	return &unsafeMapStore[Key, Value]{store: make(map[Key]Value)}
+}
+
+func NewTreeMapStore[Key constraints.Ordered, Value any]() Storage[Key, Value] {
+	return &treeMapStore[Key, Value]{store: newTree[Key, Value](cmp.Compare[Key])}
+}
+
+func NewUnsafeTreeMapStore[Key constraints.Ordered, Value any]() Storage[Key, Value] {
+	return &unsafeTreeMapStore[Key, Value]{store: newTree[Key, Value](cmp.Compare[Key])}
+}
+
+func NewSortedSliceStore[Key constraints.Ordered, Value any]() Storage[Key, Value] {
+	return newSortedSliceStore[Key, Value](cmp.Compare[Key])
+}
+
+func NewBitSetStore[Key constraints.Integer]() Storage[Key, struct{}] {
+	return &bitSetStore[Key]{}
+}
+
+func NewRoaringStore[Key ~uint32]() Storage[Key, struct{}] {
+	return &roaringStore[Key]{}
+}
+
+func NewShardedMapStore[Key comparable, Value any]() Storage[Key, Value] {
+	return newShardedMapStore[Key, Value](4)
+}
+
+// MapShardSize is the size of a shard of the sharded map store, which must be a multiple of CacheLineSize.
+const MapShardSize, CacheLineSize = unsafe.Sizeof(mapShard[string, int]{}), cacheLineSize
+
+func NewPersistentMapStore[Key comparable, Value any]() Storage[Key, Value] {
+	return &persistentMapStore[Key, Value]{store: newHamt[Key, Value]()}
+}
+
+// NewCollidingPersistentMapStore returns a persistent map store hashing keys to only 7 values,
+// sharing the same prefix up to the last level, exercising deep tries and full hash collisions.
+func NewCollidingPersistentMapStore[Value any]() Storage[int, Value] {
+	store := newHamt[int, Value]()
+	store.hash = func(key int) uint64 { return uint64(key%7) << 59 }
+	return &persistentMapStore[int, Value]{store: store}
+}
+
+func NewPersistentTreeMapStore[Key constraints.Ordered, Value any]() Storage[Key, Value] {
+	return &persistentTreeMapStore[Key, Value]{store: newTree[Key, Value](cmp.Compare[Key])}
+}
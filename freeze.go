@@ -0,0 +1,71 @@
+package kset
+
+// readOnlyKeySet is a view of a key set hiding its mutating methods.
+// Embedding the read-only interface only promotes its methods, so the view cannot be asserted back into a KeySet.
+type readOnlyKeySet[Key any] struct {
+	ReadOnlyKeySet[Key]
+}
+
+// readOnlyKeyValueSet is a view of a key-value set hiding its mutating methods.
+type readOnlyKeyValueSet[Key comparable, Value any] struct {
+	ReadOnlyKeyValueSet[Key, Value]
+}
+
+// readOnlyOrderedKeySet is a view of an ordered key set hiding its mutating methods, while keeping its navigation methods.
+type readOnlyOrderedKeySet[Key any] struct {
+	ReadOnlyOrderedKeySet[Key]
+}
+
+// readOnlyOrderedKeyValueSet is a view of an ordered key-value set hiding its mutating methods, while keeping its navigation methods.
+type readOnlyOrderedKeyValueSet[Key comparable, Value any] struct {
+	ReadOnlyOrderedKeyValueSet[Key, Value]
+}
+
+// frozenSet is implemented by read-only views, exposing the set they wrap to the algorithms optimized for its storage.
+type frozenSet[Key any] interface {
+	unfreeze() ReadOnlySet[Key]
+}
+
+func (r readOnlyKeySet[Key]) unfreeze() ReadOnlySet[Key] {
+	return r.ReadOnlyKeySet
+}
+
+func (r readOnlyKeyValueSet[Key, Value]) unfreeze() ReadOnlySet[Key] {
+	return r.ReadOnlyKeyValueSet
+}
+
+func (r readOnlyOrderedKeySet[Key]) unfreeze() ReadOnlySet[Key] {
+	return r.ReadOnlyOrderedKeySet
+}
+
+func (r readOnlyOrderedKeyValueSet[Key, Value]) unfreeze() ReadOnlySet[Key] {
+	return r.ReadOnlyOrderedKeyValueSet
+}
+
+// unfreeze returns the set wrapped by a read-only view, or the set itself if it is not a view.
+func unfreeze[Key any](set ReadOnlySet[Key]) ReadOnlySet[Key] {
+	if frozen, ok := set.(frozenSet[Key]); ok {
+		return frozen.unfreeze()
+	}
+	return set
+}
+
+// Freeze returns a read-only view of the set, sharing its storage.
+func (k *keySet[Key, Store]) Freeze() ReadOnlyKeySet[Key] {
+	return readOnlyKeySet[Key]{k}
+}
+
+// Freeze returns a read-only view of the set, sharing its storage, implementing ReadOnlyOrderedKeySet.
+func (k *orderedKeySet[Key, Store]) Freeze() ReadOnlyKeySet[Key] {
+	return readOnlyOrderedKeySet[Key]{k}
+}
+
+// Freeze returns a read-only view of the set, sharing its storage.
+func (k *keyValueSet[Key, Value, Store]) Freeze() ReadOnlyKeyValueSet[Key, Value] {
+	return readOnlyKeyValueSet[Key, Value]{k}
+}
+
+// Freeze returns a read-only view of the set, sharing its storage, implementing ReadOnlyOrderedKeyValueSet.
+func (k *orderedKeyValueSet[Key, Value, Store]) Freeze() ReadOnlyKeyValueSet[Key, Value] {
+	return readOnlyOrderedKeyValueSet[Key, Value]{k}
+}
@@ -0,0 +1,127 @@
+package kset_test
+
+import (
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_KeySet_Freeze(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
+		t.Run("shares storage", func(t *testing.T) {
+			set := constructor(1, 2)
+			frozen := set.Freeze()
+
+			set.Append(3)
+
+			assert.Equal(t, 3, frozen.Len())
+			assert.True(t, frozen.ContainsKeys(3))
+		})
+
+		t.Run("cannot be asserted back", func(t *testing.T) {
+			var frozen any = constructor(1, 2).Freeze()
+
+			_, ok := frozen.(kset.KeySet[int])
+			assert.False(t, ok)
+			_, ok = frozen.(kset.Set[int])
+			assert.False(t, ok)
+		})
+
+		t.Run("clone is mutable", func(t *testing.T) {
+			set := constructor(1, 2)
+			clone := set.Freeze().Clone()
+
+			clone.Append(3)
+
+			assert.ElementsMatch(t, []int{1, 2, 3}, clone.Slice())
+			assert.ElementsMatch(t, []int{1, 2}, set.Slice())
+		})
+
+		t.Run("algebra", func(t *testing.T) {
+			a := constructor(1, 2, 3).Freeze()
+			b := constructor(2, 3, 4).Freeze()
+
+			assert.ElementsMatch(t, []int{1, 2, 3, 4}, a.Union(b).Slice())
+			assert.ElementsMatch(t, []int{2, 3}, a.Intersect(b).Slice())
+			assert.ElementsMatch(t, []int{1}, a.Difference(b).Slice())
+			assert.ElementsMatch(t, []int{1, 4}, a.SymmetricDifference(b).Slice())
+			assert.True(t, a.Intersects(b))
+			assert.False(t, a.IsSubset(b))
+			assert.True(t, a.Equal(constructor(3, 2, 1)))
+		})
+	})
+
+	t.Run("mixed backends", func(t *testing.T) {
+		a := kset.TreeMapKey(1, 2, 3)
+		b := kset.BitSetKey(2, 3, 4)
+
+		assert.Equal(t, []int{1}, a.Difference(b.Freeze()).Slice())
+		assert.ElementsMatch(t, []int{1, 2, 3, 4}, b.Union(a.Freeze()).Slice())
+	})
+}
+
+func Test_KeyValueSet_Freeze(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.KeyValueSet[int, int]) {
+		t.Run("shares storage", func(t *testing.T) {
+			set := constructor(testKeyer, 1)
+			frozen := set.Freeze()
+
+			set.Append(2)
+
+			assert.Equal(t, 2, frozen.Len())
+			assert.True(t, frozen.Contains(2))
+		})
+
+		t.Run("cannot be asserted back", func(t *testing.T) {
+			var frozen any = constructor(testKeyer, 1).Freeze()
+
+			_, ok := frozen.(kset.KeyValueSet[int, int])
+			assert.False(t, ok)
+			_, ok = frozen.(kset.Set[int])
+			assert.False(t, ok)
+		})
+
+		t.Run("algebra", func(t *testing.T) {
+			a := constructor(testKeyer, 1, 2).Freeze()
+			b := constructor(testKeyer, 2, 3).Freeze()
+
+			assert.ElementsMatch(t, []int{1, 2, 3}, a.Union(b).Slice())
+			assert.ElementsMatch(t, []int{2}, a.Intersect(b).Slice())
+			assert.ElementsMatch(t, []int{1}, a.Difference(b).Slice())
+			assert.ElementsMatch(t, []int{1, 3}, a.SymmetricDifference(b).Slice())
+		})
+	})
+}
+
+func Test_OrderedKeySet_Freeze(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		var frozen any = constructor(3, 1, 2).Freeze()
+
+		view, ok := frozen.(kset.ReadOnlyOrderedKeySet[int])
+		assert.True(t, ok)
+		key, _ := view.Min()
+		assert.Equal(t, 1, key)
+		key, _ = view.At(2)
+		assert.Equal(t, 3, key)
+
+		_, ok = frozen.(kset.OrderedKeySet[int])
+		assert.False(t, ok)
+		assert.Equal(t, []int{1, 2}, kset.Filter(view, func(k int) bool { return k < 3 }).Slice())
+	})
+}
+
+func Test_OrderedKeyValueSet_Freeze(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		var frozen any = constructor(testKeyer, 3, 1, 2).Freeze()
+
+		view, ok := frozen.(kset.ReadOnlyOrderedKeyValueSet[int, int])
+		assert.True(t, ok)
+		value, _ := view.Max()
+		assert.Equal(t, 3, value)
+		assert.Equal(t, 1, view.Rank(2))
+
+		_, ok = frozen.(kset.OrderedKeyValueSet[int, int])
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,247 @@
+package kset
+
+import (
+	"iter"
+)
+
+// Collection is the constraint of the sets accepted by Filter, Partition, Reduce, Any and All.
+// The elements of key sets are their keys, while the elements of key-value sets are their values.
+// It is satisfied by KeySet, KeyValueSet, their ordered and read-only variants, and only by sets of this package.
+type Collection[E any] interface {
+	Len() int
+	Slice() []E
+	collection[E]
+}
+
+// collection is implemented by the sets of this package, exposing their elements to the package functions.
+type collection[E any] interface {
+	// elements iterates through the elements of the set.
+	elements() iter.Seq[E]
+	// split returns an independent set with the same storage holding the elements kept by keep,
+	// and if withRest is true, another one holding the remaining elements.
+	split(keep func(E) bool, withRest bool) (matched, rest any)
+}
+
+// Filter returns a new set with the elements of the set kept by keep, using the same storage.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Active: true}, User{ID: 2})
+//	active := kset.Filter(users, func(u User) bool { return u.Active }) // active is {{1, true}}
+func Filter[E any, S Collection[E]](set S, keep func(E) bool) S {
+	matched, _ := set.split(keep, false)
+	return matched.(S)
+}
+
+// Partition splits the set into a new set with the elements kept by keep, and another with the remaining ones.
+// Both sets use the same storage as the given set.
+// Example:
+//
+//	s := kset.TreeMapKey(1, 2, 3, 4)
+//	even, odd := kset.Partition(s, func(k int) bool { return k%2 == 0 }) // even is {2, 4}, odd is {1, 3}
+func Partition[E any, S Collection[E]](set S, keep func(E) bool) (matched, rest S) {
+	m, r := set.split(keep, true)
+	return m.(S), r.(S)
+}
+
+// Reduce combines the elements of the set into a single result, calling fn with the accumulated result and each element.
+// The order of the elements is not guaranteed, except for ordered sets, which are reduced in ascending order of their keys.
+// The set is not copied, so fn must not modify it.
+// Example:
+//
+//	s := kset.HashMapKey(1, 2, 3)
+//	sum := kset.Reduce(s, 0, func(sum, k int) int { return sum + k }) // sum is 6
+func Reduce[E, R any, S Collection[E]](set S, initial R, fn func(result R, element E) R) R {
+	result := initial
+	for element := range set.elements() {
+		result = fn(result, element)
+	}
+	return result
+}
+
+// Any reports whether fn returns true for at least one element of the set, stopping at the first one.
+// The set is not copied, so fn must not modify it.
+// Example:
+//
+//	s := kset.HashMapKey(1, 2, 3)
+//	hasEven := kset.Any(s, func(k int) bool { return k%2 == 0 }) // hasEven is true
+func Any[E any, S Collection[E]](set S, fn func(E) bool) bool {
+	for element := range set.elements() {
+		if fn(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// All reports whether fn returns true for every element of the set, stopping at the first one it does not.
+// It returns true for empty sets. The set is not copied, so fn must not modify it.
+// Example:
+//
+//	s := kset.HashMapKey(1, 2, 3)
+//	positive := kset.All(s, func(k int) bool { return k > 0 }) // positive is true
+func All[E any, S Collection[E]](set S, fn func(E) bool) bool {
+	for element := range set.elements() {
+		if !fn(element) {
+			return false
+		}
+	}
+	return true
+}
+
+// MapKeys returns a new set with the keys of the set converted by fn.
+// The set keeps its storage if fn returns keys of the same type that the storage can hold, otherwise the result is a HashMapKey set.
+// Ordered sets keeping their storage return ordered sets. The set is not copied, so fn must not modify it.
+// Example:
+//
+//	s := kset.HashMapKey(1, 2, 3)
+//	names := kset.MapKeys(s, strconv.Itoa) // names is {"1", "2", "3"}
+func MapKeys[Key any, Mapped comparable](set ReadOnlyKeySet[Key], fn func(Key) Mapped) KeySet[Mapped] {
+	keys := make([]Mapped, 0, set.Len())
+	for key := range keysIn[Key](set) {
+		keys = append(keys, fn(key))
+	}
+
+	if same, ok := unfreeze[Key](set).(interface {
+		with(keys []Mapped) (KeySet[Mapped], bool)
+	}); ok {
+		if mapped, ok := same.with(keys); ok {
+			return mapped
+		}
+	}
+	return HashMapKey(keys...)
+}
+
+// MapValues returns a new set with the values of the set converted by fn, keyed by the given selector.
+// The set keeps its storage if fn returns values of the same type and selector keys of the same type,
+// otherwise the result is a HashMapKeyValue set. Ordered sets keeping their storage return ordered sets.
+// The set is not copied, so fn must not modify it.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Email: "a@x.com"})
+//	byEmail := kset.MapValues(users, func(u User) User { return u }, func(u User) string { return u.Email })
+func MapValues[Key comparable, Value any, MappedKey comparable, Mapped any](
+	set ReadOnlyKeyValueSet[Key, Value],
+	fn func(Value) Mapped,
+	selector func(Mapped) MappedKey,
+) KeyValueSet[MappedKey, Mapped] {
+	values := make([]Mapped, 0, set.Len())
+	for _, value := range entriesIn(set) {
+		values = append(values, fn(value))
+	}
+
+	if same, ok := unfreeze[Key](set).(interface {
+		with(selector func(Mapped) MappedKey, values []Mapped) KeyValueSet[MappedKey, Mapped]
+	}); ok {
+		return same.with(selector, values)
+	}
+	return HashMapKeyValue(selector, values...)
+}
+
+func (k *keySet[Key, Store]) elements() iter.Seq[Key] {
+	return k.storeKeys()
+}
+
+func (k *keySet[Key, Store]) split(keep func(Key) bool, withRest bool) (matched, rest any) {
+	// Both sets are derived from the same clone, so concurrent writers cannot make them overlap.
+	m := k.Clone().(*keySet[Key, Store])
+
+	var kept, removed []Key
+	for key := range m.store.Iter() {
+		if keep(key) {
+			kept = append(kept, key)
+		} else {
+			removed = append(removed, key)
+		}
+	}
+
+	if !withRest {
+		m.store.Delete(removed...)
+		return m, nil
+	}
+	r := m.Clone().(*keySet[Key, Store])
+	m.store.Delete(removed...)
+	r.store.Delete(kept...)
+	return m, r
+}
+
+// with returns a new set with the same storage holding the keys, or false if the storage cannot hold one of them.
+func (k *keySet[Key, Store]) with(keys []Key) (KeySet[Key], bool) {
+	if validateKeys(k.store, keys) != nil {
+		return nil, false
+	}
+	set := &keySet[Key, Store]{store: k.store.Clone().(Store)}
+	set.store.Clear()
+	upsertMany(set.store, keys, nil)
+	return set, true
+}
+
+func (k *orderedKeySet[Key, Store]) split(keep func(Key) bool, withRest bool) (matched, rest any) {
+	m, r := k.keySet.split(keep, withRest)
+	matched = k.wrap(m.(KeySet[Key]))
+	if withRest {
+		rest = k.wrap(r.(KeySet[Key]))
+	}
+	return matched, rest
+}
+
+func (k *orderedKeySet[Key, Store]) with(keys []Key) (KeySet[Key], bool) {
+	set, ok := k.keySet.with(keys)
+	if !ok {
+		return nil, false
+	}
+	return k.wrap(set), true
+}
+
+func (k *keyValueSet[Key, Value, Store]) elements() iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for _, value := range k.store.Iter() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+func (k *keyValueSet[Key, Value, Store]) split(keep func(Value) bool, withRest bool) (matched, rest any) {
+	// Both sets are derived from the same clone, so concurrent writers cannot make them overlap.
+	m := &keyValueSet[Key, Value, Store]{store: k.store.Clone().(Store), selector: k.selector}
+
+	var kept, removed []Key
+	for key, value := range m.store.Iter() {
+		if keep(value) {
+			kept = append(kept, key)
+		} else {
+			removed = append(removed, key)
+		}
+	}
+
+	if !withRest {
+		m.store.Delete(removed...)
+		return m, nil
+	}
+	r := &keyValueSet[Key, Value, Store]{store: m.store.Clone().(Store), selector: k.selector}
+	m.store.Delete(removed...)
+	r.store.Delete(kept...)
+	return m, r
+}
+
+// with returns a new set with the same storage holding the values, keyed by selector.
+func (k *keyValueSet[Key, Value, Store]) with(selector func(Value) Key, values []Value) KeyValueSet[Key, Value] {
+	set := &keyValueSet[Key, Value, Store]{store: k.store.Clone().(Store), selector: selector}
+	set.store.Clear()
+	upsertMany(set.store, Select(selector, values...), values)
+	return set
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) split(keep func(Value) bool, withRest bool) (matched, rest any) {
+	m, r := k.keyValueSet.split(keep, withRest)
+	matched = k.wrap(m.(KeyValueSet[Key, Value]))
+	if withRest {
+		rest = k.wrap(r.(KeyValueSet[Key, Value]))
+	}
+	return matched, rest
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) with(selector func(Value) Key, values []Value) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.with(selector, values))
+}
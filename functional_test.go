@@ -0,0 +1,161 @@
+package kset_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isEven(k int) bool { return k%2 == 0 }
+
+func Test_Filter(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1, 2, 3, 4)
+
+		even := kset.Filter(set, isEven)
+		assert.ElementsMatch(t, []int{2, 4}, even.Slice())
+		assert.ElementsMatch(t, []int{1, 2, 3, 4}, set.Slice())
+		assert.IsType(t, set, even)
+
+		frozen := kset.Filter(set.Freeze(), isEven)
+		assert.ElementsMatch(t, []int{2, 4}, frozen.Slice())
+	})
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 0}, counter{3, 30})
+
+		hit := kset.Filter(set, func(c counter) bool { return c.Hits > 0 })
+		assert.Equal(t, map[int]counter{1: {1, 10}, 3: {3, 30}}, hit.Map())
+		assert.IsType(t, set, hit)
+
+		// Derived sets keep the selector of the set.
+		hit.Append(counter{4, 40})
+		assert.True(t, hit.ContainsKeys(4))
+		assert.Equal(t, 3, set.Len())
+	})
+}
+
+func Test_Filter_Ordered(t *testing.T) {
+	keys := kset.Filter(kset.TreeMapKey(4, 3, 2, 1), isEven)
+	assert.Equal(t, []int{2, 4}, keys.Slice())
+	first, _ := keys.Min()
+	assert.Equal(t, 2, first)
+
+	values := kset.Filter(kset.SortedSliceKeyValue(counterID, counter{2, 1}, counter{1, 1}, counter{3, 0}), func(c counter) bool { return c.Hits > 0 })
+	assert.Equal(t, []counter{{1, 1}, {2, 1}}, values.Slice())
+}
+
+func Test_Partition(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		even, odd := kset.Partition(constructor(1, 2, 3, 4, 5), isEven)
+		assert.ElementsMatch(t, []int{2, 4}, even.Slice())
+		assert.ElementsMatch(t, []int{1, 3, 5}, odd.Slice())
+
+		// The sets must be independent from each other.
+		even.Append(6)
+		assert.False(t, odd.ContainsKeys(6))
+	})
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		hit, missed := kset.Partition(constructor(counterID, counter{1, 10}, counter{2, 0}).Freeze(), func(c counter) bool { return c.Hits > 0 })
+		assert.Equal(t, map[int]counter{1: {1, 10}}, hit.Map())
+		assert.Equal(t, map[int]counter{2: {2, 0}}, missed.Map())
+	})
+}
+
+func Test_Reduce(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1, 2, 3)
+		assert.Equal(t, 6, kset.Reduce(set, 0, func(sum, k int) int { return sum + k }))
+		assert.Equal(t, 10, kset.Reduce(constructor(), 10, func(sum, k int) int { return sum + k }))
+	})
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 20})
+		assert.Equal(t, 30, kset.Reduce(set, 0, func(sum int, c counter) int { return sum + c.Hits }))
+	})
+
+	ordered := kset.TreeMapKey("c", "a", "b")
+	assert.Equal(t, "abc", kset.Reduce(ordered, "", func(s, k string) string { return s + k }))
+}
+
+func Test_AnyAll(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1, 2, 3)
+		assert.True(t, kset.Any(set, isEven))
+		assert.False(t, kset.All(set, isEven))
+		assert.True(t, kset.All(set.Freeze(), func(k int) bool { return k > 0 }))
+
+		assert.False(t, kset.Any(constructor(), isEven))
+		assert.True(t, kset.All(constructor(), isEven))
+	})
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 0})
+		assert.True(t, kset.Any(set, func(c counter) bool { return c.Hits == 0 }))
+		assert.False(t, kset.All(set.Freeze(), func(c counter) bool { return c.Hits > 0 }))
+	})
+}
+
+func Test_MapKeys(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1, 2, 3)
+
+		doubled := kset.MapKeys(set, func(k int) int { return k * 2 })
+		assert.ElementsMatch(t, []int{2, 4, 6}, doubled.Slice())
+		assert.ElementsMatch(t, []int{1, 2, 3}, set.Slice())
+
+		names := kset.MapKeys(set.Freeze(), strconv.Itoa)
+		assert.ElementsMatch(t, []string{"1", "2", "3"}, names.Slice())
+	})
+
+	// Sets keep their storage when the key type is unchanged.
+	bits := kset.MapKeys(kset.BitSetKey(1, 2), func(k int) int { return k + 1 })
+	assert.Equal(t, []int{2, 3}, bits.Slice())
+	assert.IsType(t, kset.BitSetKey[int](), bits)
+
+	// Keys the storage cannot hold fall back to a HashMapKey set.
+	shifted := kset.MapKeys(kset.BitSetKey(1, 2), func(k int) int { return k - 5 })
+	assert.ElementsMatch(t, []int{-4, -3}, shifted.Slice())
+	assert.IsType(t, kset.HashMapKey[int](), shifted)
+
+	// Ordered sets stay ordered when they keep their storage.
+	tree := kset.TreeMapKey(3, 1, 2)
+	for _, mapped := range []kset.KeySet[int]{
+		kset.MapKeys(tree, func(k int) int { return -k }),
+		kset.MapKeys(tree.Freeze(), func(k int) int { return -k }),
+	} {
+		ordered, ok := mapped.(kset.OrderedKeySet[int])
+		require.True(t, ok)
+		minKey, _ := ordered.Min()
+		assert.Equal(t, -3, minKey)
+	}
+}
+
+func Test_MapValues(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 20})
+
+		reset := kset.MapValues(set, func(c counter) counter { return counter{c.ID, 0} }, counterID)
+		assert.Equal(t, map[int]counter{1: {1, 0}, 2: {2, 0}}, reset.Map())
+		assert.Equal(t, map[int]counter{1: {1, 10}, 2: {2, 20}}, set.Map())
+
+		// Values can be re-keyed, merging the ones mapped to the same key.
+		byHits := kset.MapValues(set.Freeze(), func(c counter) counter { return counter{c.ID, 5} }, func(c counter) int { return c.Hits })
+		assert.Equal(t, 1, byHits.Len())
+
+		labels := kset.MapValues(set, func(c counter) string { return strconv.Itoa(c.Hits) }, func(s string) string { return s })
+		assert.ElementsMatch(t, []string{"10", "20"}, labels.Slice())
+	})
+
+	// Ordered sets stay ordered when they keep their storage.
+	tree := kset.TreeMapKeyValue(counterID, counter{1, 10}, counter{2, 20})
+	reset := kset.MapValues(tree, func(c counter) counter { return counter{-c.ID, 0} }, counterID)
+	ordered, ok := reset.(kset.OrderedKeyValueSet[int, counter])
+	require.True(t, ok)
+	minValue, _ := ordered.Min()
+	assert.Equal(t, counter{-2, 0}, minValue)
+}
@@ -1,9 +1,8 @@
 module github.com/sonalys/kset
 
-go 1.23
+go 1.24
 
 require (
-	github.com/igrmk/treemap/v2 v2.0.1
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/exp v0.0.0-20220317015231-48e79f11773a
 )
@@ -0,0 +1,259 @@
+package kset
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// hamtBits is the number of hash bits consumed by each level of the trie.
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamt is a persistent hash array mapped trie.
+// Each level branches on 5 bits of the key hash, storing only the present children in a compressed array.
+// Nodes are never modified once created: mutations copy the path from the root, sharing the rest of the trie.
+type hamt[Key comparable, Value any] struct {
+	root *hamtNode[Key, Value]
+	hash func(Key) uint64
+	size int
+}
+
+type hamtNode[Key comparable, Value any] struct {
+	// bitmap has one bit for each of the 32 possible children, children only holds the ones that are set.
+	bitmap   uint32
+	children []hamtChild[Key, Value]
+}
+
+// hamtChild is either a sub-trie or a leaf.
+type hamtChild[Key comparable, Value any] struct {
+	node *hamtNode[Key, Value]
+	leaf *hamtLeaf[Key, Value]
+}
+
+// hamtLeaf holds the entries with the same hash. There is more than one entry only on full hash collisions.
+type hamtLeaf[Key comparable, Value any] struct {
+	hash    uint64
+	entries []hamtEntry[Key, Value]
+}
+
+type hamtEntry[Key comparable, Value any] struct {
+	key   Key
+	value Value
+}
+
+func newHamt[Key comparable, Value any]() *hamt[Key, Value] {
+	seed := maphash.MakeSeed()
+	return &hamt[Key, Value]{
+		hash: func(key Key) uint64 {
+			return maphash.Comparable(seed, key)
+		},
+	}
+}
+
+// position returns the bit of the hash at the given shift, and the index of the child in the compressed array.
+func (n *hamtNode[Key, Value]) position(hash uint64, shift uint) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// with returns a copy of the node, replacing the child at index i.
+func (n *hamtNode[Key, Value]) with(i int, child hamtChild[Key, Value]) *hamtNode[Key, Value] {
+	node := &hamtNode[Key, Value]{
+		bitmap:   n.bitmap,
+		children: slices.Clone(n.children),
+	}
+	node.children[i] = child
+	return node
+}
+
+// share returns a trie sharing all nodes with this one in O(1).
+func (h *hamt[Key, Value]) share() *hamt[Key, Value] {
+	clone := *h
+	return &clone
+}
+
+func (h *hamt[Key, Value]) Len() int {
+	return h.size
+}
+
+func (h *hamt[Key, Value]) Clear() {
+	h.root = nil
+	h.size = 0
+}
+
+func (h *hamt[Key, Value]) Get(key Key) (Value, bool) {
+	hash := h.hash(key)
+	for n, shift := h.root, uint(0); n != nil; shift += hamtBits {
+		bit, i := n.position(hash, shift)
+		if n.bitmap&bit == 0 {
+			break
+		}
+		child := n.children[i]
+		if child.node != nil {
+			n = child.node
+			continue
+		}
+		if child.leaf.hash == hash {
+			for _, entry := range child.leaf.entries {
+				if entry.key == key {
+					return entry.value, true
+				}
+			}
+		}
+		break
+	}
+	var zero Value
+	return zero, false
+}
+
+func (h *hamt[Key, Value]) Contains(key Key) bool {
+	_, ok := h.Get(key)
+	return ok
+}
+
+// Upsert inserts or replaces the value of the key, copying the modified path. It returns true if the key is new.
+func (h *hamt[Key, Value]) Upsert(key Key, value Value) bool {
+	var inserted bool
+	hash := h.hash(key)
+	if h.root == nil {
+		h.root = &hamtNode[Key, Value]{}
+	}
+	h.root = h.upsert(h.root, hash, 0, hamtEntry[Key, Value]{key: key, value: value}, &inserted)
+	if inserted {
+		h.size++
+	}
+	return inserted
+}
+
+func (h *hamt[Key, Value]) upsert(n *hamtNode[Key, Value], hash uint64, shift uint, entry hamtEntry[Key, Value], inserted *bool) *hamtNode[Key, Value] {
+	bit, i := n.position(hash, shift)
+
+	if n.bitmap&bit == 0 {
+		*inserted = true
+		leaf := &hamtLeaf[Key, Value]{hash: hash, entries: []hamtEntry[Key, Value]{entry}}
+		return &hamtNode[Key, Value]{
+			bitmap:   n.bitmap | bit,
+			children: slices.Insert(slices.Clone(n.children), i, hamtChild[Key, Value]{leaf: leaf}),
+		}
+	}
+
+	child := n.children[i]
+	switch {
+	case child.node != nil:
+		return n.with(i, hamtChild[Key, Value]{node: h.upsert(child.node, hash, shift+hamtBits, entry, inserted)})
+	case child.leaf.hash == hash:
+		entries := slices.Clone(child.leaf.entries)
+		if j := slices.IndexFunc(entries, func(e hamtEntry[Key, Value]) bool { return e.key == entry.key }); j >= 0 {
+			entries[j] = entry
+		} else {
+			entries = append(entries, entry)
+			*inserted = true
+		}
+		return n.with(i, hamtChild[Key, Value]{leaf: &hamtLeaf[Key, Value]{hash: hash, entries: entries}})
+	default:
+		// Both hashes share the prefix up to this level, so the existing leaf is pushed one level down.
+		node := newLeafNode(child.leaf, shift+hamtBits)
+		return n.with(i, hamtChild[Key, Value]{node: h.upsert(node, hash, shift+hamtBits, entry, inserted)})
+	}
+}
+
+// newLeafNode returns a node holding only the leaf.
+func newLeafNode[Key comparable, Value any](leaf *hamtLeaf[Key, Value], shift uint) *hamtNode[Key, Value] {
+	return &hamtNode[Key, Value]{
+		bitmap:   1 << ((leaf.hash >> shift) & hamtMask),
+		children: []hamtChild[Key, Value]{{leaf: leaf}},
+	}
+}
+
+// Delete removes the key, copying the modified path. It returns true if the key was found.
+// Nothing is copied when the key is missing.
+func (h *hamt[Key, Value]) Delete(key Key) bool {
+	if h.root == nil {
+		return false
+	}
+	var deleted bool
+	h.root = h.delete(h.root, h.hash(key), 0, key, &deleted)
+	if deleted {
+		h.size--
+	}
+	return deleted
+}
+
+// delete returns the node without the key, or nil if it became empty.
+// Sub-tries left with a single leaf are collapsed into it, keeping the trie as shallow as possible.
+func (h *hamt[Key, Value]) delete(n *hamtNode[Key, Value], hash uint64, shift uint, key Key, deleted *bool) *hamtNode[Key, Value] {
+	bit, i := n.position(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n
+	}
+
+	var replacement hamtChild[Key, Value]
+	switch child := n.children[i]; {
+	case child.node != nil:
+		node := h.delete(child.node, hash, shift+hamtBits, key, deleted)
+		if !*deleted {
+			return n
+		}
+		if node != nil && len(node.children) == 1 && node.children[0].leaf != nil {
+			replacement.leaf = node.children[0].leaf
+		} else {
+			replacement.node = node
+		}
+	case child.leaf.hash == hash:
+		j := slices.IndexFunc(child.leaf.entries, func(e hamtEntry[Key, Value]) bool { return e.key == key })
+		if j < 0 {
+			return n
+		}
+		*deleted = true
+		if len(child.leaf.entries) > 1 {
+			replacement.leaf = &hamtLeaf[Key, Value]{
+				hash:    hash,
+				entries: slices.Delete(slices.Clone(child.leaf.entries), j, j+1),
+			}
+		}
+	default:
+		return n
+	}
+
+	if replacement.node != nil || replacement.leaf != nil {
+		return n.with(i, replacement)
+	}
+	if len(n.children) == 1 {
+		return nil
+	}
+	return &hamtNode[Key, Value]{
+		bitmap:   n.bitmap &^ bit,
+		children: slices.Delete(slices.Clone(n.children), i, i+1),
+	}
+}
+
+// All iterates through the entries in hash order.
+func (h *hamt[Key, Value]) All() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		h.root.all(yield)
+	}
+}
+
+func (n *hamtNode[Key, Value]) all(yield func(Key, Value) bool) bool {
+	if n == nil {
+		return true
+	}
+	for _, child := range n.children {
+		if child.node != nil {
+			if !child.node.all(yield) {
+				return false
+			}
+			continue
+		}
+		for _, entry := range child.leaf.entries {
+			if !yield(entry.key, entry.value) {
+				return false
+			}
+		}
+	}
+	return true
+}
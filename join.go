@@ -0,0 +1,97 @@
+package kset
+
+import "iter"
+
+// Pair holds the values joined for a key from two sets.
+// HasLeft and HasRight report whether the key is present in each set,
+// as outer joins leave the value of the missing side zero.
+type Pair[A, B any] struct {
+	Left     A
+	Right    B
+	HasLeft  bool
+	HasRight bool
+}
+
+// InnerJoin iterates through the keys present in both sets, pairing their values.
+// The smaller set drives the join, probing the other one for each of its keys,
+// so keys are yielded in its iteration order, ascending for ordered sets.
+// Use maps.Collect to gather the result into a map.
+// The sets are read while the loop runs, without being copied, so the loop body must not modify them.
+//
+// Complexity: O(min(N, M)) probes.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1}, User{ID: 2})
+//	orders := kset.HashMapKeyValue(func(o Order) int { return o.UserID }, Order{UserID: 2})
+//	for id, pair := range kset.InnerJoin(users, orders) {
+//		fmt.Println(id, pair.Left, pair.Right) // Prints 2 {2} {2}
+//	}
+func InnerJoin[Key comparable, A, B any](left ReadOnlyKeyValueSet[Key, A], right ReadOnlyKeyValueSet[Key, B]) iter.Seq2[Key, Pair[A, B]] {
+	return func(yield func(Key, Pair[A, B]) bool) {
+		if left.Len() <= right.Len() {
+			for key, a := range entriesIn(left) {
+				if b, ok := right.Get(key); ok && !yield(key, Pair[A, B]{Left: a, Right: b, HasLeft: true, HasRight: true}) {
+					return
+				}
+			}
+			return
+		}
+
+		for key, b := range entriesIn(right) {
+			if a, ok := left.Get(key); ok && !yield(key, Pair[A, B]{Left: a, Right: b, HasLeft: true, HasRight: true}) {
+				return
+			}
+		}
+	}
+}
+
+// LeftJoin iterates through the keys of the left set, pairing their values with the ones of the right set, if present.
+// Keys are yielded in the iteration order of the left set, ascending for ordered sets.
+// The sets are read while the loop runs, without being copied, so the loop body must not modify them.
+//
+// Complexity: O(N) probes, N being the size of the left set.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1}, User{ID: 2})
+//	orders := kset.HashMapKeyValue(func(o Order) int { return o.UserID }, Order{UserID: 2})
+//	for id, pair := range kset.LeftJoin(users, orders) {
+//		fmt.Println(id, pair.HasRight) // Prints 1 false and 2 true, in some order
+//	}
+func LeftJoin[Key comparable, A, B any](left ReadOnlyKeyValueSet[Key, A], right ReadOnlyKeyValueSet[Key, B]) iter.Seq2[Key, Pair[A, B]] {
+	return func(yield func(Key, Pair[A, B]) bool) {
+		for key, a := range entriesIn(left) {
+			b, ok := right.Get(key)
+			if !yield(key, Pair[A, B]{Left: a, Right: b, HasLeft: true, HasRight: ok}) {
+				return
+			}
+		}
+	}
+}
+
+// FullOuterJoin iterates through the keys present in either set, pairing their values.
+// The keys of the left set are yielded first, in its iteration order, followed by the keys only present in the right set.
+// The sets are read while the loop runs, without being copied, so the loop body must not modify them.
+//
+// Complexity: O(N+M) probes.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1})
+//	orders := kset.HashMapKeyValue(func(o Order) int { return o.UserID }, Order{UserID: 2})
+//	for id, pair := range kset.FullOuterJoin(users, orders) {
+//		fmt.Println(id, pair.HasLeft, pair.HasRight) // Prints 1 true false, then 2 false true
+//	}
+func FullOuterJoin[Key comparable, A, B any](left ReadOnlyKeyValueSet[Key, A], right ReadOnlyKeyValueSet[Key, B]) iter.Seq2[Key, Pair[A, B]] {
+	return func(yield func(Key, Pair[A, B]) bool) {
+		for key, pair := range LeftJoin(left, right) {
+			if !yield(key, pair) {
+				return
+			}
+		}
+
+		for key, b := range entriesIn(right) {
+			if !left.ContainsKeys(key) && !yield(key, Pair[A, B]{Right: b, HasRight: true}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package kset_test
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	UserID int
+	Total  float64
+}
+
+func orderUserID(o order) int { return o.UserID }
+
+type joined = kset.Pair[counter, order]
+
+func Test_InnerJoin(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		left := constructor(counterID, counter{1, 10}, counter{2, 20}, counter{3, 30})
+		expected := map[int]joined{
+			2: {Left: counter{2, 20}, Right: order{2, 1.5}, HasLeft: true, HasRight: true},
+			3: {Left: counter{3, 30}, Right: order{3, 2.5}, HasLeft: true, HasRight: true},
+		}
+
+		// Both the left and the right set must be able to drive the join.
+		small := kset.HashMapKeyValue(orderUserID, order{2, 1.5}, order{3, 2.5})
+		assert.Equal(t, expected, maps.Collect(kset.InnerJoin(left, small)))
+
+		large := kset.HashMapKeyValue(orderUserID, order{2, 1.5}, order{3, 2.5}, order{4, 3.5}, order{5, 4.5})
+		assert.Equal(t, expected, maps.Collect(kset.InnerJoin(left.Freeze(), large)))
+
+		assert.Empty(t, maps.Collect(kset.InnerJoin(left, kset.HashMapKeyValue(orderUserID))))
+	})
+}
+
+func Test_LeftJoin(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		left := constructor(counterID, counter{1, 10}, counter{2, 20})
+		right := kset.HashMapKeyValue(orderUserID, order{2, 1.5}, order{3, 2.5})
+
+		assert.Equal(t, map[int]joined{
+			1: {Left: counter{1, 10}, HasLeft: true},
+			2: {Left: counter{2, 20}, Right: order{2, 1.5}, HasLeft: true, HasRight: true},
+		}, maps.Collect(kset.LeftJoin(left, right)))
+	})
+}
+
+func Test_FullOuterJoin(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		left := constructor(counterID, counter{1, 10}, counter{2, 20})
+		right := kset.HashMapKeyValue(orderUserID, order{2, 1.5}, order{3, 2.5})
+
+		assert.Equal(t, map[int]joined{
+			1: {Left: counter{1, 10}, HasLeft: true},
+			2: {Left: counter{2, 20}, Right: order{2, 1.5}, HasLeft: true, HasRight: true},
+			3: {Right: order{3, 2.5}, HasRight: true},
+		}, maps.Collect(kset.FullOuterJoin(left, right)))
+	})
+}
+
+func Test_Join_Order(t *testing.T) {
+	left := kset.TreeMapKeyValue(counterID, counter{3, 0}, counter{1, 0}, counter{2, 0})
+	right := kset.TreeMapKeyValue(orderUserID, order{UserID: 5}, order{UserID: 2}, order{UserID: 4}, order{UserID: 1})
+
+	var keys []int
+	for key := range kset.FullOuterJoin(left, right) {
+		keys = append(keys, key)
+		if len(keys) == 4 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, keys)
+
+	keys = nil
+	for key := range kset.InnerJoin(left, right) {
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []int{1, 2}, keys)
+}
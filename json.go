@@ -0,0 +1,99 @@
+package kset
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+
+	"golang.org/x/exp/constraints"
+)
+
+// jsonNull is the JSON literal decoded as an empty set, as encoding/json decodes it into slices and maps.
+const jsonNull = "null"
+
+func (k *keySet[Key, Store]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.Slice())
+}
+
+func (k *keySet[Key, Store]) UnmarshalJSON(data []byte) error {
+	var keys []Key
+	if string(data) != jsonNull {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return err
+		}
+	}
+	return replaceKeys(k, func(add func([]Key) error) error {
+		return add(keys)
+	})
+}
+
+func (k *keyValueSet[Key, Value, Store]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.Slice())
+}
+
+func (k *keyValueSet[Key, Value, Store]) UnmarshalJSON(data []byte) error {
+	var values []Value
+	if string(data) != jsonNull {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+	}
+	return replaceValues(k, func(add func([]Value) error) error {
+		return add(values)
+	})
+}
+
+// SortedJSON returns a json.Marshaler encoding the keys of the set as a JSON array in ascending order,
+// regardless of its storage. It gives hash sets a deterministic encoding, such as for API payloads or golden files.
+// Example:
+//
+//	s := kset.HashMapKey(3, 1, 2)
+//	data, err := json.Marshal(kset.SortedJSON(s)) // data is [1,2,3]
+func SortedJSON[Key constraints.Ordered](set ReadOnlyKeySet[Key]) json.Marshaler {
+	return sortedKeySetJSON[Key]{set}
+}
+
+// SortedKeyValueJSON returns a json.Marshaler encoding the values of the set as a JSON array in ascending order of their keys,
+// regardless of its storage. It gives hash sets a deterministic encoding, such as for API payloads or golden files.
+// Example:
+//
+//	s := kset.HashMapKeyValue(func(v int) int { return v }, 3, 1, 2)
+//	data, err := json.Marshal(kset.SortedKeyValueJSON(s)) // data is [1,2,3]
+func SortedKeyValueJSON[Key constraints.Ordered, Value any](set ReadOnlyKeyValueSet[Key, Value]) json.Marshaler {
+	return sortedKeyValueSetJSON[Key, Value]{set}
+}
+
+type sortedKeySetJSON[Key constraints.Ordered] struct {
+	set ReadOnlyKeySet[Key]
+}
+
+func (s sortedKeySetJSON[Key]) MarshalJSON() ([]byte, error) {
+	keys := s.set.Slice()
+	slices.Sort(keys)
+	return json.Marshal(keys)
+}
+
+type sortedKeyValueSetJSON[Key constraints.Ordered, Value any] struct {
+	set ReadOnlyKeyValueSet[Key, Value]
+}
+
+func (s sortedKeyValueSetJSON[Key, Value]) MarshalJSON() ([]byte, error) {
+	type entry struct {
+		key   Key
+		value Value
+	}
+
+	entries := make([]entry, 0, s.set.Len())
+	for key, value := range entriesIn(s.set) {
+		entries = append(entries, entry{key, value})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	values := make([]Value, len(entries))
+	for i := range entries {
+		values[i] = entries[i].value
+	}
+	return json.Marshal(values)
+}
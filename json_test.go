@@ -0,0 +1,130 @@
+package kset_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeySet_JSON(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
+		t.Run("round trip", func(t *testing.T) {
+			data, err := json.Marshal(constructor(3, 1, 2))
+			require.NoError(t, err)
+
+			decoded := constructor()
+			require.NoError(t, json.Unmarshal(data, decoded))
+			assert.ElementsMatch(t, []int{1, 2, 3}, decoded.Slice())
+		})
+
+		t.Run("empty", func(t *testing.T) {
+			data, err := json.Marshal(constructor())
+			require.NoError(t, err)
+			assert.JSONEq(t, `[]`, string(data))
+		})
+
+		t.Run("replaces elements", func(t *testing.T) {
+			set := constructor(1, 2)
+			require.NoError(t, json.Unmarshal([]byte(`[2, 3, 3]`), set))
+			assert.ElementsMatch(t, []int{2, 3}, set.Slice())
+		})
+
+		t.Run("invalid data", func(t *testing.T) {
+			set := constructor(1, 2)
+			require.Error(t, json.Unmarshal([]byte(`["a"]`), set))
+			assert.ElementsMatch(t, []int{1, 2}, set.Slice())
+		})
+
+		t.Run("null", func(t *testing.T) {
+			set := constructor(1, 2)
+			require.NoError(t, json.Unmarshal([]byte(`null`), set))
+			assert.True(t, set.IsEmpty())
+		})
+
+		t.Run("sorted", func(t *testing.T) {
+			data, err := json.Marshal(kset.SortedJSON(constructor(3, 1, 2)))
+			require.NoError(t, err)
+			assert.Equal(t, `[1,2,3]`, string(data))
+		})
+
+		t.Run("frozen", func(t *testing.T) {
+			data, err := json.Marshal(constructor(1).Freeze())
+			require.NoError(t, err)
+			assert.Equal(t, `[1]`, string(data))
+		})
+	})
+
+	t.Run("ordered storage", func(t *testing.T) {
+		forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+			data, err := json.Marshal(constructor(3, 1, 2))
+			require.NoError(t, err)
+			assert.Equal(t, `[1,2,3]`, string(data))
+		})
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		payload := struct {
+			Tags kset.KeySet[string] `json:"tags"`
+		}{
+			Tags: kset.TreeMapKey[string](),
+		}
+
+		require.NoError(t, json.Unmarshal([]byte(`{"tags": ["b", "a"]}`), &payload))
+		assert.Equal(t, []string{"a", "b"}, payload.Tags.Slice())
+
+		_, isOrdered := payload.Tags.(kset.OrderedKeySet[string])
+		assert.True(t, isOrdered)
+	})
+
+	t.Run("keys rejected by the storage", func(t *testing.T) {
+		set := kset.BitSetKey(1, 2, 3)
+		require.ErrorIs(t, json.Unmarshal([]byte(`[4, -1]`), set), kset.ErrInvalidKey)
+		assert.Equal(t, []int{1, 2, 3}, set.Slice())
+	})
+}
+
+func Test_KeyValueSet_JSON(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	userID := func(u user) int { return u.ID }
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.KeyValueSet[int, user]) {
+		t.Run("round trip", func(t *testing.T) {
+			data, err := json.Marshal(constructor(userID, user{ID: 2, Name: "Bob"}, user{ID: 1, Name: "Alice"}))
+			require.NoError(t, err)
+
+			decoded := constructor(userID)
+			require.NoError(t, json.Unmarshal(data, decoded))
+			assert.Equal(t, map[int]user{1: {ID: 1, Name: "Alice"}, 2: {ID: 2, Name: "Bob"}}, decoded.Map())
+		})
+
+		t.Run("selects keys", func(t *testing.T) {
+			set := constructor(userID, user{ID: 9})
+			require.NoError(t, json.Unmarshal([]byte(`[{"id": 1, "name": "Alice"}, {"id": 1, "name": "Alice Smith"}]`), set))
+			assert.Equal(t, map[int]user{1: {ID: 1, Name: "Alice Smith"}}, set.Map())
+		})
+
+		t.Run("invalid data", func(t *testing.T) {
+			set := constructor(userID, user{ID: 1})
+			require.Error(t, json.Unmarshal([]byte(`{"id": 2}`), set))
+			assert.Equal(t, map[int]user{1: {ID: 1}}, set.Map())
+		})
+
+		t.Run("null", func(t *testing.T) {
+			set := constructor(userID, user{ID: 1})
+			require.NoError(t, json.Unmarshal([]byte(`null`), set))
+			assert.True(t, set.IsEmpty())
+		})
+
+		t.Run("sorted", func(t *testing.T) {
+			data, err := json.Marshal(kset.SortedKeyValueJSON(constructor(userID, user{ID: 2}, user{ID: 1})))
+			require.NoError(t, err)
+			assert.JSONEq(t, `[{"id": 1, "name": ""}, {"id": 2, "name": ""}]`, string(data))
+		})
+	})
+}
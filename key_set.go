@@ -1,6 +1,7 @@
 package kset
 
 import (
+	"io"
 	"iter"
 	"slices"
 )
@@ -10,6 +11,7 @@ import (
 // The underlying data structure used for the set is dependable on the used constructor.
 type KeySet[Key any] interface {
 	Set[Key]
+	ReadOnlyKeySet[Key]
 
 	// Append upserts multiple elements to the set.
 	// It returns the number of elements that were actually added (i.e., were not already present).
@@ -18,6 +20,102 @@ type KeySet[Key any] interface {
 	//  count := s.Append(1, 2, 3) // count is 2
 	Append(values ...Key) int
 
+	// RemoveKeys removes the specified elements from the set.
+	// Example:
+	//  s := kset.HashMapKey(1, 2, 3, 4)
+	//  s.RemoveKeys(2, 4) // s is {1, 3}
+	RemoveKeys(v ...Key)
+
+	// Pop removes and returns an arbitrary element from the set.
+	// It returns the removed element and true if the set was not empty, otherwise it returns the zero value of V and false.
+	// Example:
+	//  s := kset.HashMapKey(1, 2)
+	//  v, ok := s.Pop() // v could be 1 or 2, ok is true
+	//  v, ok = s.Pop() // v is the remaining element, ok is true
+	//  v, ok = s.Pop() // v is 0, ok is false
+	Pop() (Key, bool)
+
+	// Freeze returns a read-only view of the set, without copying it.
+	// The view has no mutating methods, and reflects later changes made through the set itself.
+	// Views of ordered sets implement ReadOnlyOrderedKeySet, which Freeze cannot be declared to return, as Go has no covariant return types.
+	// Example:
+	//  s := kset.HashMapKey(1, 2)
+	//  view := s.Freeze() // view.Append does not compile
+	//  s.Append(3) // view is {1, 2, 3}
+	Freeze() ReadOnlyKeySet[Key]
+
+	// UnmarshalJSON replaces the elements of the set with the keys of a JSON array, keeping its storage.
+	// Decoding into a set created by the desired constructor chooses the backend. It follows the decoding rules of Set,
+	// returning ErrInvalidKey for keys the storage cannot hold.
+	// Example:
+	//  s := kset.TreeMapKey[int]()
+	//  err := json.Unmarshal([]byte(`[3, 1, 2]`), s) // s is {1, 2, 3}
+	UnmarshalJSON(data []byte) error
+
+	// UnmarshalBinary replaces the elements of the set with keys encoded by MarshalBinary, keeping its storage.
+	// It follows the decoding rules of Set, returning ErrInvalidKey for keys the storage cannot hold.
+	// Example:
+	//  s := kset.TreeMapKey[int]()
+	//  err := s.UnmarshalBinary(data)
+	UnmarshalBinary(data []byte) error
+
+	// GobDecode decodes the set from gob, through UnmarshalBinary.
+	// Example:
+	//  s := kset.TreeMapKey[int]()
+	//  err := gob.NewDecoder(r).Decode(s)
+	GobDecode(data []byte) error
+
+	// ReadFrom replaces the elements of the set with keys streamed by WriteTo, returning the number of bytes read.
+	// Chunks are loaded into the storage as they are read, and checked against their checksums.
+	// Nothing is read past the end of the stream. It follows the decoding rules of Set, returning ErrInvalidKey for keys the storage cannot hold,
+	// so thread-safe sets hold their write lock while reading.
+	// Example:
+	//  s := kset.TreeMapKey[int]()
+	//  n, err := s.ReadFrom(file)
+	ReadFrom(r io.Reader) (int64, error)
+
+	// Update runs fn in a transaction, and returns its error.
+	// The changes made through tx are kept if fn returns nil, and rolled back if it returns an error or panics.
+	// On HashMapKey, TreeMapKey, SortedSliceKey, BitSetKey and RoaringKey sets, fn runs with the write lock of the set held,
+	// so it sees a consistent view and other goroutines never observe its changes half-applied.
+	// On PersistentHashMapKey and PersistentTreeMapKey sets, fn modifies a private version of the set, published when it returns,
+	// so other writers wait for it while readers keep seeing the previous version.
+	// ShardedHashMapKey sets and custom storages still roll back failed transactions, but are not isolated from concurrent writers.
+	// fn must only access the set through tx, as calling the set itself from fn can deadlock.
+	// Example:
+	//  s := kset.HashMapKey(1, 4)
+	//  err := s.Update(func(tx kset.KeyTxn[int]) error {
+	//  	if tx.ContainsKeys(1) {
+	//  		tx.RemoveKeys(1)
+	//  		tx.Append(2, 3)
+	//  	}
+	//  	return nil
+	//  }) // s is {2, 3, 4}
+	Update(fn func(tx KeyTxn[Key]) error) error
+
+	// Observe registers fn to be called with every change made to the set, and returns a function unregistering it.
+	// Callbacks run synchronously before the mutating method returns, receiving the events in the order the changes were applied,
+	// even when the set is modified by concurrent goroutines.
+	// Keys appended when already present are not reported, and Clear reports a single EventClear.
+	// Transactions report their changes once committed. Changes made before fn is registered are not reported.
+	// fn can read the set, but must not modify it, as that would deadlock.
+	// Example:
+	//  s := kset.HashMapKey(1)
+	//  cancel := s.Observe(func(e kset.Event[int, struct{}]) {
+	//  	fmt.Println(e.Kind, e.Key)
+	//  })
+	//  defer cancel()
+	//  s.Append(1, 2) // prints "insert 2"
+	//  s.RemoveKeys(1) // prints "delete 1"
+	Observe(fn func(Event[Key, struct{}])) (cancel func())
+}
+
+// ReadOnlyKeySet is the subset of KeySet that does not mutate it.
+// Operations deriving new sets, such as Union and Clone, still return independent mutable sets.
+type ReadOnlyKeySet[Key any] interface {
+	ReadOnlySet[Key]
+	collection[Key]
+
 	// Clone creates a shallow copy of the set.
 	// Example:
 	//  s1 := kset.HashMapKey(1, 2)
@@ -31,7 +129,7 @@ type KeySet[Key any] interface {
 	//  s1 := kset.HashMapKey(1, 2, 3)
 	//  s2 := kset.HashMapKey(3, 4, 5)
 	//  diff := s1.Difference(s2) // diff is {1, 2}
-	Difference(other Set[Key]) KeySet[Key]
+	Difference(other ReadOnlySet[Key]) KeySet[Key]
 
 	// DifferenceKeys returns a new set, not containing the given keys.
 	// Example:
@@ -45,36 +143,21 @@ type KeySet[Key any] interface {
 	//  s1 := kset.HashMapKey(1, 2, 3)
 	//  s2 := kset.HashMapKey(3, 4, 5)
 	//  intersection := s1.Intersect(s2) // intersection is {3}
-	Intersect(other Set[Key]) KeySet[Key]
-
-	// RemoveKeys removes the specified elements from the set.
-	// Example:
-	//  s := kset.HashMapKey(1, 2, 3, 4)
-	//  s.RemoveKeys(2, 4) // s is {1, 3}
-	RemoveKeys(v ...Key)
+	Intersect(other ReadOnlySet[Key]) KeySet[Key]
 
 	// SymmetricDifference returns a new set containing elements that are in either the current set or the other set, but not both.
 	// Example:
 	//  s1 := kset.HashMapKey(1, 2, 3)
 	//  s2 := kset.HashMapKey(3, 4, 5)
 	//  symDiff := s1.SymmetricDifference(s2) // symDiff is {1, 2, 4, 5}
-	SymmetricDifference(other KeySet[Key]) KeySet[Key]
+	SymmetricDifference(other ReadOnlyKeySet[Key]) KeySet[Key]
 
 	// Union returns a new set containing all elements from both the current set and the other set.
 	// Example:
 	//  s1 := kset.HashMapKey(1, 2)
 	//  s2 := kset.HashMapKey(2, 3)
 	//  union := s1.Union(s2) // union is {1, 2, 3}
-	Union(other KeySet[Key]) KeySet[Key]
-
-	// Pop removes and returns an arbitrary element from the set.
-	// It returns the removed element and true if the set was not empty, otherwise it returns the zero value of V and false.
-	// Example:
-	//  s := kset.HashMapKey(1, 2)
-	//  v, ok := s.Pop() // v could be 1 or 2, ok is true
-	//  v, ok = s.Pop() // v is the remaining element, ok is true
-	//  v, ok = s.Pop() // v is 0, ok is false
-	Pop() (Key, bool)
+	Union(other ReadOnlyKeySet[Key]) KeySet[Key]
 
 	// Slice returns a slice containing all elements of the set.
 	// The order of elements in the slice is not guaranteed.
@@ -82,22 +165,65 @@ type KeySet[Key any] interface {
 	//  s := kset.HashMapKey(3, 1, 2)
 	//  slice := s.Slice() // slice could be []int{1, 2, 3}, []int{3, 1, 2}, etc.
 	Slice() []Key
+
+	// MarshalJSON encodes the set as a JSON array of its keys.
+	// Sets with ordered storages, such as TreeMapKey, are encoded in ascending order.
+	// Use SortedJSON for a deterministic order regardless of the storage.
+	// Example:
+	//  s := kset.TreeMapKey(3, 1, 2)
+	//  data, err := json.Marshal(s) // data is [1,2,3]
+	MarshalJSON() ([]byte, error)
+
+	// MarshalBinary encodes the keys of the set in a compact versioned format.
+	// Integer keys are encoded as varints, and delta encoded when iterated in ascending order, as from ordered sets.
+	// Strings are encoded as is, while other keys are encoded with encoding/gob.
+	// Example:
+	//  s := kset.TreeMapKey(1, 2, 3)
+	//  data, err := s.MarshalBinary() // data is 6 bytes long
+	MarshalBinary() ([]byte, error)
+
+	// GobEncode encodes the set to gob, through MarshalBinary.
+	// Example:
+	//  s := kset.TreeMapKey(1, 2, 3)
+	//  err := gob.NewEncoder(w).Encode(s)
+	GobEncode() ([]byte, error)
+
+	// WriteTo streams the keys of the set to w in checksummed chunks, encoded like MarshalBinary, returning the number of bytes written.
+	// The set is iterated while writing without being copied, holding the read lock of thread-safe sets,
+	// so writers of sets other than PersistentHashMapKey and PersistentTreeMapKey block until WriteTo returns.
+	// Example:
+	//  s := kset.TreeMapKey(1, 2, 3)
+	//  n, err := s.WriteTo(file)
+	WriteTo(w io.Writer) (int64, error)
 }
 
 // keySet is an implementation of KeySet.
 // K is the key, must be ordered.
 // S is just a generic type parameter for removing the store abstraction and accessing the implementation directly.
 type keySet[Key any, Store Storage[Key, empty]] struct {
-	store Store
+	store     Store
+	observers observers[Key, empty]
+}
+
+// NewKeySet creates a key set on top of the given storage, appending the given keys to it.
+// It allows custom backends to be used, the storage is not copied and is owned by the set after this call.
+// Example:
+//
+//	s := kset.NewKeySet(myStorage, 1, 2, 3)
+func NewKeySet[Key any](storage Storage[Key, struct{}], keys ...Key) KeySet[Key] {
+	set := &keySet[Key, Storage[Key, empty]]{
+		store: storage,
+	}
+	set.Append(keys...)
+	return set
 }
 
 // Append adds keys to the set. Returns the number of new keys added.
 func (k *keySet[Key, Store]) Append(keys ...Key) int {
-	prevLen := k.store.Len()
-	for _, key := range keys {
-		k.store.Upsert(key, empty{})
+	if k.observers.active() {
+		return k.appendObserved(keys)
 	}
-	return k.store.Len() - prevLen
+	return countTrue(upsertMany(k.store, keys, nil))
 }
 
 // Len returns the number of keys in the set.
@@ -107,6 +233,10 @@ func (k *keySet[Key, Store]) Len() int {
 
 // Clear removes all keys from the set.
 func (k *keySet[Key, Store]) Clear() {
+	if k.observers.active() {
+		k.clearObserved()
+		return
+	}
 	k.store.Clear()
 }
 
@@ -133,7 +263,7 @@ func (k *keySet[Key, Store]) ContainsAnyKey(keys ...Key) bool {
 }
 
 // Intersects checks if this set shares any keys with the other set.
-func (k *keySet[Key, Store]) Intersects(other Set[Key]) bool {
+func (k *keySet[Key, Store]) Intersects(other ReadOnlySet[Key]) bool {
 	for key := range k.store.Iter() {
 		if other.ContainsKeys(key) {
 			return true
@@ -143,9 +273,16 @@ func (k *keySet[Key, Store]) Intersects(other Set[Key]) bool {
 }
 
 // Difference returns a new set with keys in this set but not in the other.
-func (k *keySet[Key, Store]) Difference(other Set[Key]) KeySet[Key] {
+func (k *keySet[Key, Store]) Difference(other ReadOnlySet[Key]) KeySet[Key] {
+	if store, ok := combineStore(k.store, other, mergeDifference); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+	if store, ok := mergeStore(k.store, other, mergeDifference, false); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+
 	diff := k.Clone()
-	diff.RemoveKeys(bufferedCollect(other.Keys(), other.Len())...)
+	diff.RemoveKeys(bufferedCollect(keysIn(other), other.Len())...)
 	return diff
 }
 
@@ -156,7 +293,7 @@ func (k *keySet[Key, Store]) DifferenceKeys(keys ...Key) KeySet[Key] {
 }
 
 // Equal checks if this set is equal to another set (contains the same keys).
-func (k *keySet[Key, Store]) Equal(other Set[Key]) bool {
+func (k *keySet[Key, Store]) Equal(other ReadOnlySet[Key]) bool {
 	if k.Len() != other.Len() {
 		return false
 	}
@@ -169,11 +306,19 @@ func (k *keySet[Key, Store]) Equal(other Set[Key]) bool {
 }
 
 // Intersect returns a new set with keys common to both this set and the other.
-func (k *keySet[Key, Store]) Intersect(other Set[Key]) KeySet[Key] {
+func (k *keySet[Key, Store]) Intersect(other ReadOnlySet[Key]) KeySet[Key] {
+	if store, ok := combineStore(k.store, other, mergeIntersection); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+	if store, ok := mergeStore(k.store, other, mergeIntersection, false); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+
 	intersection := k.Clone()
 
 	outerKeys := make([]Key, 0, other.Len())
-	for key := range k.store.Iter() {
+	// The clone is scanned instead of the set, so the result is consistent under concurrent writes.
+	for key := range keysIn[Key](intersection) {
 		if !other.ContainsKeys(key) {
 			outerKeys = append(outerKeys, key)
 		}
@@ -190,17 +335,17 @@ func (k *keySet[Key, Store]) IsEmpty() bool {
 }
 
 // IsProperSubset checks if this set is a proper subset of the other set.
-func (k *keySet[Key, Store]) IsProperSubset(other Set[Key]) bool {
+func (k *keySet[Key, Store]) IsProperSubset(other ReadOnlySet[Key]) bool {
 	return k.Len() < other.Len() && k.IsSubset(other)
 }
 
 // IsProperSuperset checks if this set is a proper superset of the other set.
-func (k *keySet[Key, Store]) IsProperSuperset(other Set[Key]) bool {
+func (k *keySet[Key, Store]) IsProperSuperset(other ReadOnlySet[Key]) bool {
 	return k.Len() > other.Len() && k.IsSuperset(other)
 }
 
 // IsSubset checks if this set is a subset of the other set.
-func (k *keySet[Key, Store]) IsSubset(other Set[Key]) bool {
+func (k *keySet[Key, Store]) IsSubset(other ReadOnlySet[Key]) bool {
 	if k.Len() > other.Len() {
 		return false
 	}
@@ -213,7 +358,7 @@ func (k *keySet[Key, Store]) IsSubset(other Set[Key]) bool {
 }
 
 // IsSuperset checks if this set is a superset of the other set.
-func (k *keySet[Key, Store]) IsSuperset(other Set[Key]) bool {
+func (k *keySet[Key, Store]) IsSuperset(other ReadOnlySet[Key]) bool {
 	return other.IsSubset(k)
 }
 
@@ -228,36 +373,78 @@ func (k *keySet[Key, Store]) Iter() iter.Seq[Key] {
 	}
 }
 
-// Iter returns an iterator for the keys in the set.
+// Keys returns an iterator for the keys in the set, iterating through a snapshot on thread-safe storages.
 func (k *keySet[Key, Store]) Keys() iter.Seq[Key] {
+	return keysOf(snapshotIter[Key, empty](k.store))
+}
+
+func (k *keySet[Key, Store]) storeKeys() iter.Seq[Key] {
 	return k.Iter()
 }
 
 // Pop removes and returns an arbitrary key from the set.
 // The second return Store indicates if a key was removed (true) or if the set was empty (false).
 func (k *keySet[Key, Store]) Pop() (Key, bool) {
-	for key := range k.store.Iter() {
-		defer k.store.Delete(key)
-		return key, true
+	if k.observers.active() {
+		return k.popObserved()
+	}
+	key, _, ok := popStore[Key, empty](k.store)
+	return key, ok
+}
+
+// popStore removes an arbitrary entry from the storage, holding its write lock if it is a lockingStorage.
+func popStore[Key, Value any](store Storage[Key, Value]) (key Key, value Value, ok bool) {
+	if locking, isLocking := store.(lockingStorage[Key, Value]); isLocking {
+		locking.locked(func(view Storage[Key, Value]) {
+			key, value, ok = popStore(view)
+		})
+		return key, value, ok
+	}
+
+	// The entry is deleted after iteration releases the lock, so another goroutine can delete it first.
+	// It is only returned by the goroutine that actually deleted it.
+	for {
+		found := false
+		for key, value = range store.Iter() {
+			found = true
+			break
+		}
+		if !found {
+			var zeroKey Key
+			var zeroValue Value
+			return zeroKey, zeroValue, false
+		}
+		if deleteMany(store, []Key{key})[0] {
+			return key, value, true
+		}
 	}
-	var zero Key
-	return zero, false
 }
 
 // Remove removes the specified keys from the set.
 func (k *keySet[Key, Store]) RemoveKeys(keys ...Key) {
+	if k.observers.active() {
+		k.removeObserved(keys)
+		return
+	}
 	k.store.Delete(keys...)
 }
 
 // SymmetricDifference returns a new set with keys in either this set or the other, but not both.
-func (k *keySet[Key, Store]) SymmetricDifference(other KeySet[Key]) KeySet[Key] {
+func (k *keySet[Key, Store]) SymmetricDifference(other ReadOnlyKeySet[Key]) KeySet[Key] {
+	if store, ok := combineStore(k.store, other, mergeSymmetricDifference); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+	if store, ok := mergeStore(k.store, other, mergeSymmetricDifference, false); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+
 	sd := k.Clone()
 
 	innerKeys := make([]Key, 0, other.Len())
 	outerKeys := make([]Key, 0, other.Len())
 
-	for key := range other.Keys() {
-		if !k.ContainsKeys(key) {
+	for key := range keysIn[Key](other) {
+		if !sd.ContainsKeys(key) {
 			outerKeys = append(outerKeys, key)
 			continue
 		}
@@ -280,9 +467,16 @@ func (k *keySet[Key, Store]) Slice() []Key {
 }
 
 // Union returns a new set with all keys from both this set and the other.
-func (k *keySet[Key, Store]) Union(other KeySet[Key]) KeySet[Key] {
+func (k *keySet[Key, Store]) Union(other ReadOnlyKeySet[Key]) KeySet[Key] {
+	if store, ok := combineStore(k.store, other, mergeUnion); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+	if store, ok := mergeStore(k.store, other, mergeUnion, false); ok {
+		return &keySet[Key, Store]{store: store}
+	}
+
 	union := k.Clone()
-	union.Append(slices.Collect(other.Keys())...)
+	union.Append(slices.Collect(keysIn[Key](other))...)
 	return union
 }
 
@@ -1,7 +1,10 @@
 package kset_test
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sonalys/kset"
 	"github.com/stretchr/testify/assert"
@@ -17,8 +20,37 @@ func forEachStoreK[K constraints.Ordered](t *testing.T, f func(t *testing.T, con
 	stores := []tc{
 		{name: "HashMapKey", f: kset.HashMapKey[K]},
 		{name: "UnsafeHashMapKey", f: kset.UnsafeHashMapKey[K]},
-		{name: "TreeMapKey", f: kset.TreeMapKey[K]},
-		{name: "UnsafeTreeMapKey", f: kset.UnsafeTreeMapKey[K]},
+		{name: "TreeMapKey", f: func(keys ...K) kset.KeySet[K] { return kset.TreeMapKey(keys...) }},
+		{name: "UnsafeTreeMapKey", f: func(keys ...K) kset.KeySet[K] { return kset.UnsafeTreeMapKey(keys...) }},
+		{name: "SortedSliceKey", f: func(keys ...K) kset.KeySet[K] { return kset.SortedSliceKey(keys...) }},
+		{name: "ShardedHashMapKey", f: func(keys ...K) kset.KeySet[K] { return kset.ShardedHashMapKey(4, keys...) }},
+		{name: "PersistentHashMapKey", f: kset.PersistentHashMapKey[K]},
+		{name: "PersistentTreeMapKey", f: func(keys ...K) kset.KeySet[K] { return kset.PersistentTreeMapKey(keys...) }},
+		{name: "NewKeySet", f: func(keys ...K) kset.KeySet[K] {
+			return kset.NewKeySet(newCustomStore[K, struct{}](), keys...)
+		}},
+	}
+
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			f(t, tc.f)
+		})
+	}
+}
+
+// forEachThreadSafeStoreK runs f against the constructors of the key sets that are safe for concurrent use.
+func forEachThreadSafeStoreK(t *testing.T, f func(t *testing.T, constructor func(keys ...int) kset.KeySet[int])) {
+	stores := []struct {
+		name string
+		f    func(keys ...int) kset.KeySet[int]
+	}{
+		{name: "HashMapKey", f: kset.HashMapKey[int]},
+		{name: "TreeMapKey", f: func(keys ...int) kset.KeySet[int] { return kset.TreeMapKey(keys...) }},
+		{name: "SortedSliceKey", f: func(keys ...int) kset.KeySet[int] { return kset.SortedSliceKey(keys...) }},
+		{name: "ShardedHashMapKey", f: func(keys ...int) kset.KeySet[int] { return kset.ShardedHashMapKey(4, keys...) }},
+		{name: "PersistentHashMapKey", f: kset.PersistentHashMapKey[int]},
+		{name: "PersistentTreeMapKey", f: func(keys ...int) kset.KeySet[int] { return kset.PersistentTreeMapKey(keys...) }},
+		{name: "BitSetKey", f: kset.BitSetKey[int]},
 	}
 
 	for _, tc := range stores {
@@ -43,9 +75,83 @@ func Test_KeySet_Append(t *testing.T) {
 			assert.Equal(t, 0, count)
 			assert.True(t, set.ContainsKeys(3))
 		})
+
+		t.Run("repeated in batch", func(t *testing.T) {
+			set := constructor(1)
+			count := set.Append(2, 2, 1, 3, 3)
+			assert.Equal(t, 2, count)
+			assert.ElementsMatch(t, []int{1, 2, 3}, set.Slice())
+		})
 	})
 }
 
+func Test_KeySet_Append_Concurrent(t *testing.T) {
+	const workers, keys = 8, 1000
+
+	forEachThreadSafeStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor()
+
+		var added atomic.Int64
+		var wg sync.WaitGroup
+		for worker := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Every worker appends overlapping batches, so most keys are appended by several workers.
+				for i := worker; i < keys; i += 10 {
+					added.Add(int64(set.Append(i, i+1, i+2, i+3, i+4)))
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, set.Len(), int(added.Load()), "the added counts must sum up to the final length")
+	})
+}
+
+func Test_KeySet_AtomicBatches(t *testing.T) {
+	const keys = 100
+
+	batch := make([]int, keys)
+	for i := range batch {
+		batch[i] = i
+	}
+	full := kset.HashMapKey(batch...)
+
+	stores := map[string]func(keys ...int) kset.KeySet[int]{
+		"HashMapKey": kset.HashMapKey[int],
+		"TreeMapKey": func(keys ...int) kset.KeySet[int] { return kset.TreeMapKey(keys...) },
+	}
+
+	for name, constructor := range stores {
+		t.Run(name, func(t *testing.T) {
+			set := constructor()
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+				for range 500 {
+					assert.Equal(t, keys, set.Append(batch...))
+					set.RemoveKeys(batch...)
+				}
+			}()
+
+			// Readers must only observe the set before or after each batch.
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				assert.Contains(t, []int{0, keys}, set.Len())
+				assert.Contains(t, []int{0, keys}, len(set.Slice()))
+				assert.Contains(t, []int{0, keys}, set.Intersect(full).Len())
+				assert.Contains(t, []int{0, keys}, set.SymmetricDifference(full).Len())
+			}
+		})
+	}
+}
+
 func Test_KeySet_Clear(t *testing.T) {
 	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
 		set := constructor(1, 2)
@@ -242,6 +348,122 @@ func Test_KeySet_Pop(t *testing.T) {
 	})
 }
 
+func Test_KeySet_Pop_Concurrent(t *testing.T) {
+	const workers, keys = 8, 1000
+
+	forEachThreadSafeStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor()
+		for i := range keys {
+			set.Append(i)
+		}
+
+		popped := make(chan int, keys)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for key, ok := set.Pop(); ok; key, ok = set.Pop() {
+					popped <- key
+				}
+			}()
+		}
+		wg.Wait()
+		close(popped)
+
+		// Every key must be popped by exactly one worker.
+		seen := kset.HashMapKey[int]()
+		for key := range popped {
+			assert.Equal(t, 1, seen.Append(key), "key %d popped more than once", key)
+		}
+		assert.Equal(t, keys, seen.Len())
+		assert.True(t, set.IsEmpty())
+	})
+}
+
+func Test_KeySet_Keys_Mutation(t *testing.T) {
+	forEachThreadSafeStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		t.Run("filter in place", func(t *testing.T) {
+			set := constructor(1, 2, 3, 4, 5, 6)
+			withoutDeadlock(t, func() {
+				for key := range set.Keys() {
+					if key%2 == 0 {
+						set.RemoveKeys(key)
+					}
+				}
+			})
+			assert.ElementsMatch(t, []int{1, 3, 5}, set.Slice())
+		})
+
+		t.Run("append during iteration", func(t *testing.T) {
+			set := constructor(1, 2, 3)
+			var visited []int
+			withoutDeadlock(t, func() {
+				for key := range set.Keys() {
+					visited = append(visited, key)
+					set.Append(key + 10)
+				}
+			})
+			assert.ElementsMatch(t, []int{1, 2, 3}, visited, "keys appended during the loop must not be visited")
+			assert.ElementsMatch(t, []int{1, 2, 3, 11, 12, 13}, set.Slice())
+		})
+
+		t.Run("algebra during iteration", func(t *testing.T) {
+			set := constructor(1, 2, 3)
+			withoutDeadlock(t, func() {
+				for range set.Keys() {
+					set.Append(set.Union(set).Slice()...)
+					set.Update(func(tx kset.KeyTxn[int]) error { return nil })
+				}
+			})
+			assert.Equal(t, 3, set.Len())
+		})
+	})
+}
+
+func Test_KeySet_EarlyStop_NoSnapshot(t *testing.T) {
+	const keys = 10000
+
+	forEachThreadSafeStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor()
+		for i := range keys {
+			set.Append(i)
+		}
+		other := set.Clone()
+
+		// Algorithms stopping at the first key must not copy the set before iterating it.
+		allocs := testing.AllocsPerRun(10, func() {
+			set.Intersects(other)
+			kset.Any(set, func(int) bool { return true })
+		})
+		assert.Less(t, allocs, float64(keys/100))
+
+		// Draining the set must not copy it on every Pop.
+		withoutDeadlock(t, func() {
+			for _, ok := set.Pop(); ok; _, ok = set.Pop() {
+			}
+		})
+		assert.True(t, set.IsEmpty())
+	})
+}
+
+// withoutDeadlock fails the test if f does not return in time.
+func withoutDeadlock(t *testing.T, f func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
 func Test_KeySet_RemoveKeys(t *testing.T) {
 	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
 		t.Run("empty", func(t *testing.T) {
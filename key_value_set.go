@@ -1,6 +1,7 @@
 package kset
 
 import (
+	"io"
 	"iter"
 	"maps"
 	"slices"
@@ -12,6 +13,7 @@ import (
 // The underlying data structure used for the set is dependable on the used constructor.
 type KeyValueSet[Key comparable, Value any] interface {
 	Set[Key]
+	ReadOnlyKeyValueSet[Key, Value]
 
 	// Append upserts multiple elements to the set.
 	// It returns the number of elements that were actually added (i.e., were not already present).
@@ -20,6 +22,162 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  count := s.Append(1, 2, 3) // count is 2
 	Append(values ...Value) int
 
+	// AppendWith appends the values, calling merge to resolve the value of the keys already present.
+	// merge receives the stored value as old and the appended one as new, and its result replaces old.
+	// It returns the number of elements that were actually added (i.e., were not already present).
+	// Each value is merged atomically on thread-safe sets, holding the lock of its key while merge runs, so merge must not access the set.
+	// The value returned by merge must have the given key, otherwise AppendWith panics.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Version: 2})
+	//  newest := func(_ int, old, new User) User {
+	//  	if new.Version > old.Version {
+	//  		return new
+	//  	}
+	//  	return old
+	//  }
+	//  count := s.AppendWith(newest, User{ID: 1, Version: 1}, User{ID: 2, Version: 1}) // count is 1, s is {{1, 2}, {2, 1}}
+	AppendWith(merge func(key Key, old, new Value) Value, values ...Value) int
+
+	// AppendIfAbsent appends the values whose keys are not present, keeping the stored values of the others.
+	// Values repeated in the call are only appended once, keeping the first one.
+	// It returns the number of elements that were actually added.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"})
+	//  count := s.AppendIfAbsent(User{ID: 1, Name: "b"}, User{ID: 2, Name: "c"}) // count is 1, s is {{1, "a"}, {2, "c"}}
+	AppendIfAbsent(values ...Value) int
+
+	// GetOrInsert returns the value stored for the key of value and true, if the key is present.
+	// Otherwise, it inserts value and returns it with false.
+	// It is atomic on thread-safe sets.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"})
+	//  u, loaded := s.GetOrInsert(User{ID: 1, Name: "b"}) // u is {1, "a"}, loaded is true
+	//  u, loaded = s.GetOrInsert(User{ID: 2, Name: "c"}) // u is {2, "c"}, loaded is false
+	GetOrInsert(value Value) (Value, bool)
+
+	// Compute calls fn with the value stored for key and whether it was found.
+	// The value returned by fn is stored for the key if fn returns true, otherwise the key is removed.
+	// It returns the value stored afterwards, and whether the key is present.
+	// It is atomic on thread-safe sets, holding the lock of the key while fn runs, so fn must not access the set.
+	// The value returned by fn must have the given key, otherwise Compute panics.
+	// Example:
+	//  hits := kset.HashMapKeyValue(func(c Counter) string { return c.Page })
+	//  hits.Compute("/home", func(old Counter, ok bool) (Counter, bool) {
+	//  	return Counter{Page: "/home", Hits: old.Hits + 1}, true
+	//  }) // hits is {{"/home", 1}}
+	Compute(key Key, fn func(old Value, ok bool) (Value, bool)) (Value, bool)
+
+	// CompareAndSwap replaces old with new if the value stored for their key is equal to old according to eq.
+	// It returns whether the value was swapped. It is atomic on thread-safe sets.
+	// old and new must have the same key, otherwise CompareAndSwap panics.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Version: 1})
+	//  eq := func(a, b User) bool { return a.Version == b.Version }
+	//  swapped := s.CompareAndSwap(User{ID: 1, Version: 1}, User{ID: 1, Version: 2}, eq) // swapped is true
+	//  swapped = s.CompareAndSwap(User{ID: 1, Version: 1}, User{ID: 1, Version: 3}, eq) // swapped is false
+	CompareAndSwap(old, new Value, eq func(a, b Value) bool) bool
+
+	// Remove removes the specified elements from the set.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3, 4)
+	//  s.Remove(2, 4) // s is {1, 3}
+	Remove(v ...Value)
+
+	// RemoveKeys removes the specified keys from the set.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3, 4)
+	//  s.RemoveKeys(2, 4) // s is {1, 3}
+	RemoveKeys(v ...Key)
+
+	// Pop removes and returns an arbitrary element from the set.
+	// It returns the removed element and true if the set was not empty, otherwise it returns the zero value of V and false.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2)
+	//  v, ok := s.Pop() // v could be 1 or 2, ok is true
+	//  v, ok = s.Pop() // v is the remaining element, ok is true
+	//  v, ok = s.Pop() // v is 0, ok is false
+	Pop() (Value, bool)
+
+	// Freeze returns a read-only view of the set, without copying it.
+	// The view has no mutating methods, and reflects later changes made through the set itself.
+	// Views of ordered sets implement ReadOnlyOrderedKeyValueSet, which Freeze cannot be declared to return, as Go has no covariant return types.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2)
+	//  view := s.Freeze() // view.Append does not compile
+	//  s.Append(3) // view is {1, 2, 3}
+	Freeze() ReadOnlyKeyValueSet[Key, Value]
+
+	// UnmarshalJSON replaces the elements of the set with the values of a JSON array, keeping its storage.
+	// Keys are derived from the decoded values through the selector of the set. It follows the decoding rules of Set.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v })
+	//  err := json.Unmarshal([]byte(`[3, 1, 2]`), s) // s is {1, 2, 3}
+	UnmarshalJSON(data []byte) error
+
+	// UnmarshalBinary replaces the elements of the set with values encoded by MarshalBinary, keeping its storage.
+	// Keys are derived from the decoded values through the selector of the set. It follows the decoding rules of Set.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v })
+	//  err := s.UnmarshalBinary(data)
+	UnmarshalBinary(data []byte) error
+
+	// GobDecode decodes the set from gob, through UnmarshalBinary.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v })
+	//  err := gob.NewDecoder(r).Decode(s)
+	GobDecode(data []byte) error
+
+	// ReadFrom replaces the elements of the set with values streamed by WriteTo, returning the number of bytes read.
+	// Chunks are loaded into the storage as they are read, and checked against their checksums.
+	// Nothing is read past the end of the stream. It follows the decoding rules of Set,
+	// so thread-safe sets hold their write lock while reading.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v })
+	//  n, err := s.ReadFrom(file)
+	ReadFrom(r io.Reader) (int64, error)
+
+	// Update runs fn in a transaction, and returns its error.
+	// The changes made through tx are kept if fn returns nil, and rolled back if it returns an error or panics.
+	// On HashMapKeyValue, TreeMapKeyValue and SortedSliceKeyValue sets, fn runs with the write lock of the set held,
+	// so it sees a consistent view and other goroutines never observe its changes half-applied.
+	// On PersistentHashMapKeyValue and PersistentTreeMapKeyValue sets, fn modifies a private version of the set, published when it returns,
+	// so other writers wait for it while readers keep seeing the previous version.
+	// ShardedHashMapKeyValue sets and custom storages still roll back failed transactions, but are not isolated from concurrent writers.
+	// fn must only access the set through tx, as calling the set itself from fn can deadlock.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 4)
+	//  err := s.Update(func(tx kset.Txn[int, int]) error {
+	//  	if tx.ContainsKeys(1) {
+	//  		tx.RemoveKeys(1)
+	//  		tx.Append(2, 3)
+	//  	}
+	//  	return nil
+	//  }) // s is {2, 3, 4}
+	Update(fn func(tx Txn[Key, Value]) error) error
+
+	// Observe registers fn to be called with every change made to the set, and returns a function unregistering it.
+	// Callbacks run synchronously before the mutating method returns, receiving the events in the order the changes were applied,
+	// even when the set is modified by concurrent goroutines.
+	// Appending a value for an existing key reports an EventReplace with the previous value in Old,
+	// and Clear reports a single EventClear.
+	// Transactions report their changes once committed. Changes made before fn is registered are not reported.
+	// fn can read the set, but must not modify it, as that would deadlock.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"})
+	//  cancel := s.Observe(func(e kset.Event[int, User]) {
+	//  	fmt.Println(e.Kind, e.Old.Name, e.Value.Name)
+	//  })
+	//  defer cancel()
+	//  s.Append(User{ID: 1, Name: "b"}) // prints "replace a b"
+	Observe(fn func(Event[Key, Value])) (cancel func())
+}
+
+// ReadOnlyKeyValueSet is the subset of KeyValueSet that does not mutate it.
+// Operations deriving new sets, such as Union and Clone, still return independent mutable sets.
+type ReadOnlyKeyValueSet[Key comparable, Value any] interface {
+	ReadOnlySet[Key]
+	collection[Value]
+
 	// Clone creates a shallow copy of the set.
 	// Example:
 	//  s1 := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2)
@@ -28,6 +186,34 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  // s1 is {1, 2}, s2 is {1, 2, 3}
 	Clone() KeyValueSet[Key, Value]
 
+	// Get returns the value stored for the key, and whether it was found.
+	// Example:
+	//  s := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"})
+	//  u, ok := s.Get(1) // u is {1, "a"}, ok is true
+	Get(key Key) (Value, bool)
+
+	// EqualValues checks if the set contains the same keys as the other set, with values equal according to eq.
+	// Unlike Equal, which only compares keys, it detects values that changed.
+	// The sets are compared through snapshots of thread-safe sets, so eq can access them, and comparing a set with itself does not deadlock.
+	// Example:
+	//  s1 := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"})
+	//  s2 := kset.HashMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "b"})
+	//  eq := func(a, b User) bool { return a == b }
+	//  s1.Equal(s2) // true
+	//  s1.EqualValues(s2, eq) // false
+	EqualValues(other ReadOnlyKeyValueSet[Key, Value], eq func(a, b Value) bool) bool
+
+	// Diff returns the changes turning the set into the other set, using eq to compare the values of their common keys.
+	// Entries are listed in the iteration order of the set they come from, ascending for ordered sets.
+	// Like EqualValues, it compares snapshots of thread-safe sets, so eq can access them.
+	// Example:
+	//  before := kset.TreeMapKeyValue(func(u User) int { return u.ID }, User{ID: 1, Name: "a"}, User{ID: 2, Name: "b"})
+	//  after := kset.TreeMapKeyValue(func(u User) int { return u.ID }, User{ID: 2, Name: "c"}, User{ID: 3, Name: "d"})
+	//  diff := before.Diff(after, func(a, b User) bool { return a == b })
+	//  // diff.Added is [{3, "d"}], diff.Removed is [{1, "a"}]
+	//  // diff.Changed is [{Key: 2, Old: {2, "b"}, New: {2, "c"}}]
+	Diff(other ReadOnlyKeyValueSet[Key, Value], eq func(a, b Value) bool) Diff[Key, Value]
+
 	// Contains checks if all specified elements are present in the set.
 	// It returns true if all elements v are in the set, false otherwise.
 	// Example:
@@ -49,7 +235,7 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  s1 := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3)
 	//  s2 := kset.HashMapKeyValue(func(v int) int { return v }, 3, 4, 5)
 	//  diff := s1.Difference(s2) // diff is {1, 2}
-	Difference(other Set[Key]) KeyValueSet[Key, Value]
+	Difference(other ReadOnlySet[Key]) KeyValueSet[Key, Value]
 
 	// DifferenceKeys returns a copy of the current set, excluding the given keys.
 	// Example:
@@ -62,10 +248,11 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  s1 := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3)
 	//  s2 := kset.HashMapKeyValue(func(v int) int { return v }, 3, 4, 5)
 	//  intersection := s1.Intersect(s2) // intersection is {3}
-	Intersect(other Set[Key]) KeyValueSet[Key, Value]
+	Intersect(other ReadOnlySet[Key]) KeyValueSet[Key, Value]
 
 	// KeyValues returns an iterator (iter.Seq) over the elements of the set.
 	// The order of iteration is not guaranteed.
+	// Thread-safe sets iterate through a snapshot taken when the loop starts, so the loop body can modify the set.
 	// Example:
 	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3)
 	//  for v := range s.KeyValues() {
@@ -73,40 +260,30 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  }
 	KeyValues() iter.Seq2[Key, Value]
 
-	// Remove removes the specified elements from the set.
-	// Example:
-	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3, 4)
-	//  s.Remove(2, 4) // s is {1, 3}
-	Remove(v ...Value)
-
-	// RemoveKeys removes the specified keys from the set.
-	// Example:
-	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3, 4)
-	//  s.RemoveKeys(2, 4) // s is {1, 3}
-	RemoveKeys(v ...Key)
-
 	// SymmetricDifference returns a new set containing elements that are in either the current set or the other set, but not both.
 	// Example:
 	//  s1 := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2, 3)
 	//  s2 := kset.HashMapKeyValue(func(v int) int { return v }, 3, 4, 5)
 	//  symDiff := s1.SymmetricDifference(s2) // symDiff is {1, 2, 4, 5}
-	SymmetricDifference(other KeyValueSet[Key, Value]) KeyValueSet[Key, Value]
+	SymmetricDifference(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value]
 
 	// Union returns a new set containing all elements from both the current set and the other set.
+	// Keys present in both sets keep the value of the other set, use UnionWith to resolve them otherwise.
 	// Example:
 	//  s1 := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2)
 	//  s2 := kset.HashMapKeyValue(func(v int) int { return v }, 2, 3)
 	//  union := s1.Union(s2) // union is {1, 2, 3}
-	Union(other KeyValueSet[Key, Value]) KeyValueSet[Key, Value]
+	Union(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value]
 
-	// Pop removes and returns an arbitrary element from the set.
-	// It returns the removed element and true if the set was not empty, otherwise it returns the zero value of V and false.
+	// UnionWith returns a new set containing all elements from both the current set and the other set,
+	// calling merge with the value of each set to resolve the keys present in both.
+	// The value returned by merge must have the given key, otherwise UnionWith panics.
 	// Example:
-	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 1, 2)
-	//  v, ok := s.Pop() // v could be 1 or 2, ok is true
-	//  v, ok = s.Pop() // v is the remaining element, ok is true
-	//  v, ok = s.Pop() // v is 0, ok is false
-	Pop() (Value, bool)
+	//  s1 := kset.HashMapKeyValue(func(c Counter) string { return c.Page }, Counter{"/", 1}, Counter{"/a", 2})
+	//  s2 := kset.HashMapKeyValue(func(c Counter) string { return c.Page }, Counter{"/", 3})
+	//  sum := func(page string, a, b Counter) Counter { return Counter{page, a.Hits + b.Hits} }
+	//  union := s1.UnionWith(s2, sum) // union is {{"/", 4}, {"/a", 2}}
+	UnionWith(other ReadOnlyKeyValueSet[Key, Value], merge func(key Key, a, b Value) Value) KeyValueSet[Key, Value]
 
 	// Slice returns a slice containing all elements of the set.
 	// The order of elements in the slice is not guaranteed.
@@ -120,20 +297,97 @@ type KeyValueSet[Key comparable, Value any] interface {
 	//  s := kset.HashMapKeyValue(func(v int) int { return v }, 3, 1, 2)
 	//  m := s.Map() // m returns map[int]int{ 1:1, 2:2, 3:3 }
 	Map() map[Key]Value
+
+	// MarshalJSON encodes the set as a JSON array of its values.
+	// Sets with ordered storages, such as TreeMapKeyValue, are encoded in ascending order of their keys.
+	// Use SortedKeyValueJSON for a deterministic order regardless of the storage.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 3, 1, 2)
+	//  data, err := json.Marshal(s) // data is [1,2,3]
+	MarshalJSON() ([]byte, error)
+
+	// MarshalBinary encodes the values of the set in a compact versioned format.
+	// Integer and string values are encoded natively, while other values are encoded with encoding/gob.
+	// Use MarshalBinaryWith to encode the values with another Codec.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3)
+	//  data, err := s.MarshalBinary() // data is 6 bytes long
+	MarshalBinary() ([]byte, error)
+
+	// GobEncode encodes the set to gob, through MarshalBinary.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3)
+	//  err := gob.NewEncoder(w).Encode(s)
+	GobEncode() ([]byte, error)
+
+	// WriteTo streams the values of the set to w in checksummed chunks, encoded like MarshalBinary, returning the number of bytes written.
+	// The set is iterated while writing without being copied, holding the read lock of thread-safe sets,
+	// so writers of sets other than PersistentHashMapKeyValue and PersistentTreeMapKeyValue block until WriteTo returns.
+	// Use WriteToWith to encode the values with another Codec.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3)
+	//  n, err := s.WriteTo(file)
+	WriteTo(w io.Writer) (int64, error)
 }
 
 type keyValueSet[Key comparable, Value any, Store Storage[Key, Value]] struct {
-	store    Store
-	selector func(Value) Key
+	store     Store
+	selector  func(Value) Key
+	observers observers[Key, Value]
+}
+
+// NewKeyValueSet creates a key-value set on top of the given storage, appending the given values to it.
+// It allows custom backends to be used, the storage is not copied and is owned by the set after this call.
+// Example:
+//
+//	s := kset.NewKeyValueSet(func(v int) int { return v }, myStorage, 1, 2, 3)
+func NewKeyValueSet[Key comparable, Value any](selector func(Value) Key, storage Storage[Key, Value], values ...Value) KeyValueSet[Key, Value] {
+	set := &keyValueSet[Key, Value, Storage[Key, Value]]{
+		store:    storage,
+		selector: selector,
+	}
+	set.Append(values...)
+	return set
 }
 
 func (k *keyValueSet[Key, Value, Store]) Append(values ...Value) int {
-	prevLen := k.store.Len()
-	for _, val := range values {
-		key := k.selector(val)
-		k.store.Upsert(key, val)
+	if k.observers.active() {
+		return k.appendObserved(Select(k.selector, values...), values)
 	}
-	return k.store.Len() - prevLen
+	return countTrue(upsertMany(k.store, Select(k.selector, values...), values))
+}
+
+func (k *keyValueSet[Key, Value, Store]) AppendWith(merge func(key Key, old, new Value) Value, values ...Value) int {
+	return k.appendWith(values, func(key Key, old, new Value) (Value, computeOp) {
+		return merge(key, old, new), computeUpsert
+	})
+}
+
+func (k *keyValueSet[Key, Value, Store]) AppendIfAbsent(values ...Value) int {
+	return k.appendWith(values, func(_ Key, old, _ Value) (Value, computeOp) {
+		return old, computeKeep
+	})
+}
+
+// appendWith inserts the values whose keys are missing, and applies the change returned by merge to the others.
+// Every value is applied atomically, so merge sees the values appended before it, even in the same call.
+func (k *keyValueSet[Key, Value, Store]) appendWith(values []Value, merge func(key Key, old, new Value) (Value, computeOp)) int {
+	added := 0
+	for _, value := range values {
+		key := k.selector(value)
+		k.compute(key, func(old Value, found bool) (Value, computeOp) {
+			if !found {
+				added++
+				return value, computeUpsert
+			}
+			merged, op := merge(key, old, value)
+			if op == computeUpsert && k.selector(merged) != key {
+				panic("kset: merge must return a value with the same key")
+			}
+			return merged, op
+		})
+	}
+	return added
 }
 
 func (k *keyValueSet[Key, Value, Store]) Len() int {
@@ -141,6 +395,10 @@ func (k *keyValueSet[Key, Value, Store]) Len() int {
 }
 
 func (k *keyValueSet[Key, Value, Store]) Clear() {
+	if k.observers.active() {
+		k.clearObserved()
+		return
+	}
 	k.store.Clear()
 }
 
@@ -184,7 +442,7 @@ func (k *keyValueSet[Key, Value, Store]) ContainsAnyKey(keys ...Key) bool {
 	return slices.ContainsFunc(keys, k.store.Contains)
 }
 
-func (k *keyValueSet[Key, Value, Store]) Intersects(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) Intersects(other ReadOnlySet[Key]) bool {
 	for key := range k.store.Iter() {
 		if other.ContainsKeys(key) {
 			return true
@@ -194,9 +452,13 @@ func (k *keyValueSet[Key, Value, Store]) Intersects(other Set[Key]) bool {
 	return false
 }
 
-func (k *keyValueSet[Key, Value, Store]) Difference(other Set[Key]) KeyValueSet[Key, Value] {
+func (k *keyValueSet[Key, Value, Store]) Difference(other ReadOnlySet[Key]) KeyValueSet[Key, Value] {
+	if store, ok := mergeStore(k.store, other, mergeDifference, true); ok {
+		return &keyValueSet[Key, Value, Store]{store: store, selector: k.selector}
+	}
+
 	diff := k.Clone()
-	diff.RemoveKeys(bufferedCollect(other.Keys(), other.Len())...)
+	diff.RemoveKeys(bufferedCollect(keysIn(other), other.Len())...)
 	return diff
 }
 
@@ -206,7 +468,7 @@ func (k *keyValueSet[Key, Value, Store]) DifferenceKeys(keys ...Key) KeyValueSet
 	return diff
 }
 
-func (k *keyValueSet[Key, Value, Store]) Equal(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) Equal(other ReadOnlySet[Key]) bool {
 	if k.Len() != other.Len() {
 		return false
 	}
@@ -220,12 +482,17 @@ func (k *keyValueSet[Key, Value, Store]) Equal(other Set[Key]) bool {
 	return true
 }
 
-func (k *keyValueSet[Key, Value, Store]) Intersect(other Set[Key]) KeyValueSet[Key, Value] {
+func (k *keyValueSet[Key, Value, Store]) Intersect(other ReadOnlySet[Key]) KeyValueSet[Key, Value] {
+	if store, ok := mergeStore(k.store, other, mergeIntersection, true); ok {
+		return &keyValueSet[Key, Value, Store]{store: store, selector: k.selector}
+	}
+
 	intersection := k.Clone()
 
 	outerKeys := make([]Key, 0, other.Len())
 
-	for key := range k.store.Iter() {
+	// The clone is scanned instead of the set, so the result is consistent under concurrent writes.
+	for key := range keysIn[Key](intersection) {
 		if !other.ContainsKeys(key) {
 			outerKeys = append(outerKeys, key)
 		}
@@ -240,15 +507,15 @@ func (k *keyValueSet[Key, Value, Store]) IsEmpty() bool {
 	return k.Len() == 0
 }
 
-func (k *keyValueSet[Key, Value, Store]) IsProperSubset(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) IsProperSubset(other ReadOnlySet[Key]) bool {
 	return k.Len() < other.Len() && k.IsSubset(other)
 }
 
-func (k *keyValueSet[Key, Value, Store]) IsProperSuperset(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) IsProperSuperset(other ReadOnlySet[Key]) bool {
 	return k.Len() > other.Len() && k.IsSuperset(other)
 }
 
-func (k *keyValueSet[Key, Value, Store]) IsSubset(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) IsSubset(other ReadOnlySet[Key]) bool {
 	if k.Len() > other.Len() {
 		return false
 	}
@@ -260,32 +527,32 @@ func (k *keyValueSet[Key, Value, Store]) IsSubset(other Set[Key]) bool {
 	return true
 }
 
-func (k *keyValueSet[Key, Value, Store]) IsSuperset(other Set[Key]) bool {
+func (k *keyValueSet[Key, Value, Store]) IsSuperset(other ReadOnlySet[Key]) bool {
 	return other.IsSubset(k)
 }
 
 func (k *keyValueSet[Key, Value, Store]) KeyValues() iter.Seq2[Key, Value] {
-	return k.store.Iter()
+	return snapshotIter[Key, Value](k.store)
 }
 
 func (k *keyValueSet[Key, Value, Store]) Keys() iter.Seq[Key] {
-	return func(yield func(Key) bool) {
-		for key := range k.store.Iter() {
-			if !yield(key) {
-				break
-			}
-		}
-	}
+	return keysOf(snapshotIter[Key, Value](k.store))
+}
+
+func (k *keyValueSet[Key, Value, Store]) storeKeys() iter.Seq[Key] {
+	return keysOf(k.store.Iter())
+}
+
+func (k *keyValueSet[Key, Value, Store]) storeEntries() iter.Seq2[Key, Value] {
+	return k.store.Iter()
 }
 
 func (k *keyValueSet[Key, Value, Store]) Pop() (Value, bool) {
-	for key, value := range k.store.Iter() {
-		defer k.store.Delete(key)
-		return value, true
+	if k.observers.active() {
+		return k.popObserved()
 	}
-
-	var zero Value
-	return zero, false
+	_, value, ok := popStore[Key, Value](k.store)
+	return value, ok
 }
 
 func (k *keyValueSet[Key, Value, Store]) Remove(values ...Value) {
@@ -293,21 +560,29 @@ func (k *keyValueSet[Key, Value, Store]) Remove(values ...Value) {
 	for _, val := range values {
 		keys = append(keys, k.selector(val))
 	}
-	k.store.Delete(keys...)
+	k.RemoveKeys(keys...)
 }
 
 func (k *keyValueSet[Key, Value, Store]) RemoveKeys(keys ...Key) {
+	if k.observers.active() {
+		k.removeObserved(keys)
+		return
+	}
 	k.store.Delete(keys...)
 }
 
-func (k *keyValueSet[Key, Value, Store]) SymmetricDifference(other KeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+func (k *keyValueSet[Key, Value, Store]) SymmetricDifference(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+	if store, ok := mergeStore(k.store, other, mergeSymmetricDifference, true); ok {
+		return &keyValueSet[Key, Value, Store]{store: store, selector: k.selector}
+	}
+
 	sd := k.Clone()
 
 	innerKeys := make([]Key, 0, other.Len())
 	outerValues := make([]Value, 0, other.Len())
 
-	for key, value := range other.KeyValues() {
-		if !k.ContainsKeys(key) {
+	for key, value := range entriesIn(other) {
+		if !sd.ContainsKeys(key) {
 			outerValues = append(outerValues, value)
 			continue
 		}
@@ -332,10 +607,23 @@ func (k *keyValueSet[Key, Value, Store]) Map() map[Key]Value {
 	return maps.Collect(k.store.Iter())
 }
 
-func (k *keyValueSet[Key, Value, Store]) Union(other KeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+func (k *keyValueSet[Key, Value, Store]) Union(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+	if store, ok := mergeStore(k.store, other, mergeUnion, true); ok {
+		return &keyValueSet[Key, Value, Store]{store: store, selector: k.selector}
+	}
+
 	union := k.Clone()
 	union.Append(other.Slice()...)
 	return union
 }
 
+func (k *keyValueSet[Key, Value, Store]) UnionWith(other ReadOnlyKeyValueSet[Key, Value], merge func(key Key, a, b Value) Value) KeyValueSet[Key, Value] {
+	union := &keyValueSet[Key, Value, Store]{
+		store:    k.store.Clone().(Store),
+		selector: k.selector,
+	}
+	union.AppendWith(merge, other.Slice()...)
+	return union
+}
+
 var _ KeyValueSet[string, string] = &keyValueSet[string, string, *safeMapStore[string, string]]{}
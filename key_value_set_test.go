@@ -1,6 +1,7 @@
 package kset_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/sonalys/kset"
@@ -21,8 +22,54 @@ func forEachStore[K constraints.Ordered, V any](t *testing.T, f func(t *testing.
 	stores := []tc{
 		{name: "HashMapKeyValue", f: kset.HashMapKeyValue[K, V]},
 		{name: "UnsafeHashMapKeyValue", f: kset.UnsafeHashMapKeyValue[K, V]},
-		{name: "TreeMapKeyValue", f: kset.TreeMapKeyValue[K, V]},
-		{name: "UnsafeTreeMapKeyValue", f: kset.UnsafeTreeMapKeyValue[K, V]},
+		{name: "TreeMapKeyValue", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.TreeMapKeyValue(selector, values...)
+		}},
+		{name: "UnsafeTreeMapKeyValue", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.UnsafeTreeMapKeyValue(selector, values...)
+		}},
+		{name: "SortedSliceKeyValue", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.SortedSliceKeyValue(selector, values...)
+		}},
+		{name: "ShardedHashMapKeyValue", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.ShardedHashMapKeyValue(4, selector, values...)
+		}},
+		{name: "PersistentHashMapKeyValue", f: kset.PersistentHashMapKeyValue[K, V]},
+		{name: "PersistentTreeMapKeyValue", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.PersistentTreeMapKeyValue(selector, values...)
+		}},
+		{name: "NewKeyValueSet", f: func(selector func(V) K, values ...V) kset.KeyValueSet[K, V] {
+			return kset.NewKeyValueSet(selector, newCustomStore[K, V](), values...)
+		}},
+	}
+
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			f(t, tc.f)
+		})
+	}
+}
+
+// forEachThreadSafeStore runs f against the constructors of the key-value sets that are safe for concurrent use.
+func forEachThreadSafeStore[V any](t *testing.T, f func(t *testing.T, constructor func(selector func(V) int, values ...V) kset.KeyValueSet[int, V])) {
+	stores := []struct {
+		name string
+		f    func(selector func(V) int, values ...V) kset.KeyValueSet[int, V]
+	}{
+		{name: "HashMapKeyValue", f: kset.HashMapKeyValue[int, V]},
+		{name: "TreeMapKeyValue", f: func(selector func(V) int, values ...V) kset.KeyValueSet[int, V] {
+			return kset.TreeMapKeyValue(selector, values...)
+		}},
+		{name: "SortedSliceKeyValue", f: func(selector func(V) int, values ...V) kset.KeyValueSet[int, V] {
+			return kset.SortedSliceKeyValue(selector, values...)
+		}},
+		{name: "ShardedHashMapKeyValue", f: func(selector func(V) int, values ...V) kset.KeyValueSet[int, V] {
+			return kset.ShardedHashMapKeyValue(4, selector, values...)
+		}},
+		{name: "PersistentHashMapKeyValue", f: kset.PersistentHashMapKeyValue[int, V]},
+		{name: "PersistentTreeMapKeyValue", f: func(selector func(V) int, values ...V) kset.KeyValueSet[int, V] {
+			return kset.PersistentTreeMapKeyValue(selector, values...)
+		}},
 	}
 
 	for _, tc := range stores {
@@ -232,6 +279,21 @@ func Test_Iter(t *testing.T) {
 	})
 }
 
+func Test_Iter_Mutation(t *testing.T) {
+	forEachThreadSafeStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.KeyValueSet[int, int]) {
+		set := constructor(testKeyer, 1, 2, 3, 4)
+		withoutDeadlock(t, func() {
+			for key, value := range set.KeyValues() {
+				if key%2 == 0 {
+					set.RemoveKeys(key)
+					set.Append(value * 10)
+				}
+			}
+		})
+		assert.ElementsMatch(t, []int{1, 3, 20, 40}, set.Slice())
+	})
+}
+
 func Test_Len(t *testing.T) {
 	forEachStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.KeyValueSet[int, int]) {
 		t.Run("empty", func(t *testing.T) {
@@ -333,3 +395,67 @@ func Test_Union(t *testing.T) {
 		})
 	})
 }
+
+func Test_UnionWith(t *testing.T) {
+	sum := func(id int, a, b counter) counter { return counter{id, a.Hits + b.Hits} }
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set1 := constructor(counterID, counter{1, 1}, counter{2, 2})
+		set2 := kset.HashMapKeyValue(counterID, counter{2, 3}, counter{3, 4})
+
+		union := set1.UnionWith(set2, sum)
+		assert.Equal(t, map[int]counter{1: {1, 1}, 2: {2, 5}, 3: {3, 4}}, union.Map())
+		assert.Equal(t, map[int]counter{1: {1, 1}, 2: {2, 2}}, set1.Map())
+
+		assert.PanicsWithValue(t, "kset: merge must return a value with the same key", func() {
+			set1.UnionWith(set2, func(int, counter, counter) counter { return counter{ID: 4} })
+		})
+	})
+}
+
+func Test_AppendWith(t *testing.T) {
+	sum := func(id int, old, new counter) counter { return counter{id, old.Hits + new.Hits} }
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 1})
+
+		count := set.AppendWith(sum, counter{1, 2}, counter{2, 3}, counter{2, 4})
+		assert.Equal(t, 1, count)
+		assert.Equal(t, map[int]counter{1: {1, 3}, 2: {2, 7}}, set.Map())
+	})
+}
+
+func Test_AppendIfAbsent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 1})
+		events := record(set.Observe)
+
+		count := set.AppendIfAbsent(counter{1, 2}, counter{2, 3}, counter{2, 4})
+		assert.Equal(t, 1, count)
+		assert.Equal(t, map[int]counter{1: {1, 1}, 2: {2, 3}}, set.Map())
+		assert.Equal(t, []counterEvent{{Kind: kset.EventInsert, Key: 2, Value: counter{2, 3}}}, events())
+	})
+}
+
+func Test_AppendWith_Concurrent(t *testing.T) {
+	const workers, appends = 8, 100
+	sum := func(id int, old, new counter) counter { return counter{id, old.Hits + new.Hits} }
+
+	forEachThreadSafeStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID)
+
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range appends {
+					set.AppendWith(sum, counter{1, 1}, counter{2, 1})
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, map[int]counter{1: {1, workers * appends}, 2: {2, workers * appends}}, set.Map())
+	})
+}
@@ -0,0 +1,179 @@
+package kset
+
+import "iter"
+
+// mergeOp is a set operation computed through a linear merge of sorted entries.
+type mergeOp int
+
+const (
+	mergeUnion mergeOp = iota
+	mergeIntersection
+	mergeDifference
+	mergeSymmetricDifference
+)
+
+type (
+	// sortedKeys is implemented by sets able to iterate through their keys in ascending order.
+	sortedKeys[Key any] interface {
+		sortedKeys() iter.Seq[Key]
+	}
+
+	// sortedEntries is implemented by sets able to iterate through their entries in ascending key order.
+	sortedEntries[Key, Value any] interface {
+		sortedEntries() iter.Seq2[Key, Value]
+	}
+)
+
+// combineStore computes the operation between store and other natively, when both sets use the same combinable storage type.
+// Otherwise, it returns false and the caller should fallback to the generic algorithm.
+func combineStore[Key any, Store Storage[Key, empty]](store Store, other ReadOnlySet[Key], op mergeOp) (Store, bool) {
+	var zero Store
+	other = unfreeze(other)
+
+	combinable, ok := any(store).(combinableStorage[Store])
+	if !ok {
+		return zero, false
+	}
+
+	otherSet, ok := other.(*keySet[Key, Store])
+	if !ok {
+		return zero, false
+	}
+
+	return combinable.combine(otherSet.store, op), true
+}
+
+// mergeStore computes the operation between store and other with a single merge pass, bulk loading the result.
+// It only succeeds when store is a sortedStorage and other iterates in ascending order according to the same comparison,
+// otherwise it returns false and the caller should fallback to the generic algorithm.
+// withValues indicates if values are relevant, for key sets they are ignored.
+//
+// Complexity: O(N+M), instead of O(N+M*logN) from probing.
+func mergeStore[Key, Value any, Store Storage[Key, Value]](store Store, other ReadOnlySet[Key], op mergeOp, withValues bool) (Store, bool) {
+	var zero Store
+	other = unfreeze(other)
+
+	sorted, ok := any(store).(sortedStorage[Key, Value])
+	if !ok {
+		return zero, false
+	}
+
+	var bKeys []Key
+	var bValues []Value
+
+	if withValues && (op == mergeUnion || op == mergeSymmetricDifference) {
+		entries, ok := other.(sortedEntries[Key, Value])
+		if !ok {
+			return zero, false
+		}
+		bKeys, bValues = collectEntries(entries.sortedEntries(), other.Len())
+	} else {
+		keys, ok := other.(sortedKeys[Key])
+		if !ok {
+			return zero, false
+		}
+		bKeys = bufferedCollect(keys.sortedKeys(), other.Len())
+	}
+
+	// Validating the order of the other set removes the need of comparing the ordering of both sets.
+	if !isStrictlyAscending(sorted.compare, bKeys) {
+		return zero, false
+	}
+
+	var aKeys []Key
+	var aValues []Value
+
+	if withValues {
+		aKeys, aValues = collectEntries(store.Iter(), store.Len())
+	} else {
+		aKeys = bufferedCollect(keysOf(store.Iter()), store.Len())
+	}
+
+	keys, values := mergeSorted(sorted.compare, op, aKeys, aValues, bKeys, bValues)
+
+	return sorted.loadSorted(keys, values).(Store), true
+}
+
+// mergeSorted computes the operation between two strictly ascending sequences of entries.
+// Values are optional, when aValues is nil the resulting values are also nil.
+// For keys present in both sides, the value from b is kept, matching the behavior of Append.
+func mergeSorted[Key, Value any](compare func(a, b Key) int, op mergeOp, aKeys []Key, aValues []Value, bKeys []Key, bValues []Value) ([]Key, []Value) {
+	withValues := aValues != nil
+
+	var capacity int
+	switch op {
+	case mergeUnion, mergeSymmetricDifference:
+		capacity = len(aKeys) + len(bKeys)
+	case mergeIntersection:
+		capacity = min(len(aKeys), len(bKeys))
+	case mergeDifference:
+		capacity = len(aKeys)
+	}
+
+	keys := make([]Key, 0, capacity)
+	var values []Value
+	if withValues {
+		values = make([]Value, 0, capacity)
+	}
+
+	appendA := func(i int) {
+		keys = append(keys, aKeys[i])
+		if withValues {
+			values = append(values, aValues[i])
+		}
+	}
+
+	appendB := func(j int) {
+		keys = append(keys, bKeys[j])
+		if withValues {
+			values = append(values, bValues[j])
+		}
+	}
+
+	keepA := op != mergeIntersection
+	keepB := op == mergeUnion || op == mergeSymmetricDifference
+
+	i, j := 0, 0
+	for i < len(aKeys) && j < len(bKeys) {
+		switch c := compare(aKeys[i], bKeys[j]); {
+		case c < 0:
+			if keepA {
+				appendA(i)
+			}
+			i++
+		case c > 0:
+			if keepB {
+				appendB(j)
+			}
+			j++
+		default:
+			switch op {
+			case mergeUnion:
+				appendB(j)
+			case mergeIntersection:
+				appendA(i)
+			}
+			i++
+			j++
+		}
+	}
+
+	for ; keepA && i < len(aKeys); i++ {
+		appendA(i)
+	}
+
+	for ; keepB && j < len(bKeys); j++ {
+		appendB(j)
+	}
+
+	return keys, values
+}
+
+func isStrictlyAscending[Key any](compare func(a, b Key) int, keys []Key) bool {
+	for i := 1; i < len(keys); i++ {
+		if compare(keys[i-1], keys[i]) >= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package kset
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind is the kind of change reported by an Event.
+type EventKind uint8
+
+const (
+	// EventInsert reports a key added to the set, with its value.
+	EventInsert EventKind = iota + 1
+	// EventReplace reports the value of an existing key being replaced, with the previous value in Old.
+	// Key sets never report it, as appending an existing key does not change them.
+	EventReplace
+	// EventDelete reports a key removed from the set, with its last value in Old.
+	EventDelete
+	// EventClear reports all keys being removed by Clear. Key, Value and Old are zero.
+	EventClear
+)
+
+func (e EventKind) String() string {
+	switch e {
+	case EventInsert:
+		return "insert"
+	case EventReplace:
+		return "replace"
+	case EventDelete:
+		return "delete"
+	case EventClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change made to a set, delivered to the callbacks registered with Observe.
+// Key sets report events with empty values.
+type Event[Key, Value any] struct {
+	Kind  EventKind
+	Key   Key
+	Value Value
+	Old   Value
+}
+
+// observers holds the callbacks registered on a set.
+// While it has callbacks, mutations of the set hold its mutex from the change until its events are delivered,
+// so callbacks receive events in the order the changes were applied, regardless of the storage.
+type observers[Key, Value any] struct {
+	mutex sync.Mutex
+	// registry guards changes to callbacks, which is replaced on every change so it can be read without locking.
+	registry  sync.Mutex
+	callbacks atomic.Pointer[[]*func(Event[Key, Value])]
+}
+
+func (o *observers[Key, Value]) load() []*func(Event[Key, Value]) {
+	if callbacks := o.callbacks.Load(); callbacks != nil {
+		return *callbacks
+	}
+	return nil
+}
+
+// active reports whether mutations must be observed.
+func (o *observers[Key, Value]) active() bool {
+	return len(o.load()) > 0
+}
+
+// add registers the callback, returning a function removing it.
+func (o *observers[Key, Value]) add(fn func(Event[Key, Value])) func() {
+	callback := &fn
+
+	o.registry.Lock()
+	defer o.registry.Unlock()
+	callbacks := append(slices.Clone(o.load()), callback)
+	o.callbacks.Store(&callbacks)
+
+	return sync.OnceFunc(func() {
+		o.registry.Lock()
+		defer o.registry.Unlock()
+		callbacks := slices.DeleteFunc(slices.Clone(o.load()), func(c *func(Event[Key, Value])) bool {
+			return c == callback
+		})
+		o.callbacks.Store(&callbacks)
+	})
+}
+
+// emit delivers the events to every callback, in order.
+// Callbacks are loaded for every event, so a callback cancelled while handling one does not receive the next.
+func (o *observers[Key, Value]) emit(events ...Event[Key, Value]) {
+	for _, event := range events {
+		for _, callback := range o.load() {
+			(*callback)(event)
+		}
+	}
+}
+
+// Observe registers fn to be called for every change made to the set, returning a function unregistering it.
+func (k *keySet[Key, Store]) Observe(fn func(Event[Key, empty])) (cancel func()) {
+	return k.observers.add(fn)
+}
+
+func (k *keySet[Key, Store]) appendObserved(keys []Key) int {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	inserted := upsertMany(k.store, keys, nil)
+	events := make([]Event[Key, empty], 0, len(keys))
+	for i, key := range keys {
+		if inserted[i] {
+			events = append(events, Event[Key, empty]{Kind: EventInsert, Key: key})
+		}
+	}
+	k.observers.emit(events...)
+	return len(events)
+}
+
+func (k *keySet[Key, Store]) removeObserved(keys []Key) {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	deleted := deleteMany(k.store, keys)
+	events := make([]Event[Key, empty], 0, len(keys))
+	for i, key := range keys {
+		if deleted[i] {
+			events = append(events, Event[Key, empty]{Kind: EventDelete, Key: key})
+		}
+	}
+	k.observers.emit(events...)
+}
+
+func (k *keySet[Key, Store]) clearObserved() {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	k.store.Clear()
+	k.observers.emit(Event[Key, empty]{Kind: EventClear})
+}
+
+func (k *keySet[Key, Store]) popObserved() (Key, bool) {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	key, _, ok := popStore[Key, empty](k.store)
+	if ok {
+		k.observers.emit(Event[Key, empty]{Kind: EventDelete, Key: key})
+	}
+	return key, ok
+}
+
+func (k *keySet[Key, Store]) updateObserved(fn func(tx KeyTxn[Key]) error) error {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	var events []Event[Key, empty]
+	err := update[Key, empty](k.store, func(j *journal[Key, empty]) error {
+		j.observed = true
+		if err := fn(k.txn(j)); err != nil {
+			return err
+		}
+		events = slices.DeleteFunc(j.events, func(event Event[Key, empty]) bool {
+			return event.Kind == EventReplace
+		})
+		return nil
+	})
+	k.observers.emit(events...)
+	return err
+}
+
+// Observe registers fn to be called for every change made to the set, returning a function unregistering it.
+func (k *keyValueSet[Key, Value, Store]) Observe(fn func(Event[Key, Value])) (cancel func()) {
+	return k.observers.add(fn)
+}
+
+func (k *keyValueSet[Key, Value, Store]) appendObserved(keys []Key, values []Value) int {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	previous := make([]Value, len(keys))
+	for i, key := range keys {
+		previous[i], _ = k.store.Get(key)
+	}
+	inserted := upsertMany(k.store, keys, values)
+
+	// Repeated keys replace the value appended before them in the same batch.
+	appended := make(map[Key]Value, len(keys))
+	events := make([]Event[Key, Value], 0, len(keys))
+	added := 0
+	for i, key := range keys {
+		event := Event[Key, Value]{Kind: EventInsert, Key: key, Value: values[i]}
+		if inserted[i] {
+			added++
+		} else {
+			event.Kind, event.Old = EventReplace, previous[i]
+			if old, ok := appended[key]; ok {
+				event.Old = old
+			}
+		}
+		appended[key] = values[i]
+		events = append(events, event)
+	}
+	k.observers.emit(events...)
+	return added
+}
+
+func (k *keyValueSet[Key, Value, Store]) removeObserved(keys []Key) {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	previous := make([]Value, len(keys))
+	for i, key := range keys {
+		previous[i], _ = k.store.Get(key)
+	}
+	deleted := deleteMany(k.store, keys)
+
+	events := make([]Event[Key, Value], 0, len(keys))
+	for i, key := range keys {
+		if deleted[i] {
+			events = append(events, Event[Key, Value]{Kind: EventDelete, Key: key, Old: previous[i]})
+		}
+	}
+	k.observers.emit(events...)
+}
+
+func (k *keyValueSet[Key, Value, Store]) clearObserved() {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	k.store.Clear()
+	k.observers.emit(Event[Key, Value]{Kind: EventClear})
+}
+
+func (k *keyValueSet[Key, Value, Store]) popObserved() (Value, bool) {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	key, value, ok := popStore[Key, Value](k.store)
+	if ok {
+		k.observers.emit(Event[Key, Value]{Kind: EventDelete, Key: key, Old: value})
+	}
+	return value, ok
+}
+
+func (k *keyValueSet[Key, Value, Store]) computeObserved(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	var old Value
+	var found bool
+	var op computeOp
+	value, ok := computeStore[Key, Value](k.store, key, func(current Value, exists bool) (Value, computeOp) {
+		old, found = current, exists
+		var value Value
+		value, op = fn(current, exists)
+		return value, op
+	})
+
+	switch {
+	case op == computeUpsert && found:
+		k.observers.emit(Event[Key, Value]{Kind: EventReplace, Key: key, Value: value, Old: old})
+	case op == computeUpsert:
+		k.observers.emit(Event[Key, Value]{Kind: EventInsert, Key: key, Value: value})
+	case op == computeDelete && found:
+		k.observers.emit(Event[Key, Value]{Kind: EventDelete, Key: key, Old: old})
+	}
+	return value, ok
+}
+
+func (k *keyValueSet[Key, Value, Store]) updateObserved(fn func(tx Txn[Key, Value]) error) error {
+	k.observers.mutex.Lock()
+	defer k.observers.mutex.Unlock()
+
+	var events []Event[Key, Value]
+	err := update[Key, Value](k.store, func(j *journal[Key, Value]) error {
+		j.observed = true
+		if err := fn(k.txn(j)); err != nil {
+			return err
+		}
+		events = j.events
+		return nil
+	})
+	k.observers.emit(events...)
+	return err
+}
@@ -0,0 +1,250 @@
+package kset_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyEvent = kset.Event[int, struct{}]
+
+type counterEvent = kset.Event[int, counter]
+
+// record registers an observer on the set, returning the events it received so far.
+func record[E any](observe func(func(E)) func()) func() []E {
+	var mutex sync.Mutex
+	var events []E
+	observe(func(event E) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	})
+	return func() []E {
+		mutex.Lock()
+		defer mutex.Unlock()
+		received := events
+		events = nil
+		return received
+	}
+}
+
+func Test_KeySet_Observe(t *testing.T) {
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1, 2)
+		events := record(set.Observe)
+
+		assert.Equal(t, 2, set.Append(2, 3, 4, 3))
+		assert.Equal(t, []keyEvent{
+			{Kind: kset.EventInsert, Key: 3},
+			{Kind: kset.EventInsert, Key: 4},
+		}, events())
+
+		set.RemoveKeys(1, 5, 1)
+		assert.Equal(t, []keyEvent{{Kind: kset.EventDelete, Key: 1}}, events())
+
+		key, ok := set.Pop()
+		require.True(t, ok)
+		assert.Equal(t, []keyEvent{{Kind: kset.EventDelete, Key: key}}, events())
+
+		set.Clear()
+		assert.Equal(t, []keyEvent{{Kind: kset.EventClear}}, events())
+
+		_, ok = set.Pop()
+		require.False(t, ok)
+		assert.Empty(t, events())
+	})
+}
+
+func Test_KeySet_Observe_Update(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		set := constructor(1)
+		events := record(set.Observe)
+
+		err := set.Update(func(tx kset.KeyTxn[int]) error {
+			tx.Append(2)
+			assert.Empty(t, events(), "events must not be reported before commit")
+			tx.Append(1, 3)
+			tx.RemoveKeys(2)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []keyEvent{
+			{Kind: kset.EventInsert, Key: 2},
+			{Kind: kset.EventInsert, Key: 3},
+			{Kind: kset.EventDelete, Key: 2},
+		}, events())
+
+		err = set.Update(func(tx kset.KeyTxn[int]) error {
+			tx.Append(4)
+			tx.RemoveKeys(1)
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+		assert.Empty(t, events())
+	})
+}
+
+func Test_KeySet_Observe_Cancel(t *testing.T) {
+	set := kset.HashMapKey(1)
+
+	var calls int
+	var cancel func()
+	cancel = set.Observe(func(keyEvent) {
+		calls++
+		// Cancelling from the callback must not deadlock.
+		cancel()
+	})
+	other := record(set.Observe)
+
+	set.Append(2, 3)
+	assert.Equal(t, 1, calls)
+	assert.Len(t, other(), 2)
+
+	cancel()
+	set.Append(4)
+	assert.Equal(t, 1, calls)
+	assert.Len(t, other(), 1)
+}
+
+func Test_KeyValueSet_Observe(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10}, counter{2, 20})
+		events := record(set.Observe)
+
+		assert.Equal(t, 1, set.Append(counter{1, 11}, counter{3, 30}, counter{1, 12}))
+		assert.Equal(t, []counterEvent{
+			{Kind: kset.EventReplace, Key: 1, Value: counter{1, 11}, Old: counter{1, 10}},
+			{Kind: kset.EventInsert, Key: 3, Value: counter{3, 30}},
+			{Kind: kset.EventReplace, Key: 1, Value: counter{1, 12}, Old: counter{1, 11}},
+		}, events())
+
+		set.Remove(counter{ID: 2}, counter{ID: 4})
+		assert.Equal(t, []counterEvent{{Kind: kset.EventDelete, Key: 2, Old: counter{2, 20}}}, events())
+
+		set.RemoveKeys(3)
+		assert.Equal(t, []counterEvent{{Kind: kset.EventDelete, Key: 3, Old: counter{3, 30}}}, events())
+
+		value, ok := set.Pop()
+		require.True(t, ok)
+		assert.Equal(t, []counterEvent{{Kind: kset.EventDelete, Key: 1, Old: value}}, events())
+
+		set.Append(counter{5, 50})
+		events()
+		set.Clear()
+		assert.Equal(t, []counterEvent{{Kind: kset.EventClear}}, events())
+	})
+}
+
+func Test_KeyValueSet_Observe_Compute(t *testing.T) {
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10})
+		events := record(set.Observe)
+
+		set.GetOrInsert(counter{1, 20})
+		assert.Empty(t, events())
+
+		set.GetOrInsert(counter{2, 20})
+		assert.Equal(t, []counterEvent{{Kind: kset.EventInsert, Key: 2, Value: counter{2, 20}}}, events())
+
+		set.Compute(1, func(old counter, ok bool) (counter, bool) {
+			return counter{1, old.Hits + 1}, true
+		})
+		assert.Equal(t, []counterEvent{{Kind: kset.EventReplace, Key: 1, Value: counter{1, 11}, Old: counter{1, 10}}}, events())
+
+		assert.False(t, set.CompareAndSwap(counter{1, 10}, counter{1, 12}, sameHits))
+		assert.Empty(t, events())
+
+		set.Compute(2, func(counter, bool) (counter, bool) { return counter{}, false })
+		assert.Equal(t, []counterEvent{{Kind: kset.EventDelete, Key: 2, Old: counter{2, 20}}}, events())
+
+		set.Compute(3, func(counter, bool) (counter, bool) { return counter{}, false })
+		assert.Empty(t, events())
+	})
+}
+
+func Test_KeyValueSet_Observe_Update(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID, counter{1, 10})
+		events := record(set.Observe)
+
+		err := set.Update(func(tx kset.Txn[int, counter]) error {
+			tx.Append(counter{1, 11}, counter{2, 20})
+			tx.RemoveKeys(1)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []counterEvent{
+			{Kind: kset.EventReplace, Key: 1, Value: counter{1, 11}, Old: counter{1, 10}},
+			{Kind: kset.EventInsert, Key: 2, Value: counter{2, 20}},
+			{Kind: kset.EventDelete, Key: 1, Old: counter{1, 11}},
+		}, events())
+
+		err = set.Update(func(tx kset.Txn[int, counter]) error {
+			tx.Append(counter{3, 30})
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+		assert.Empty(t, events())
+		assert.Equal(t, map[int]counter{2: {2, 20}}, set.Map())
+	})
+}
+
+func Test_KeyValueSet_Observe_Concurrent(t *testing.T) {
+	const workers, operations = 8, 200
+
+	forEachThreadSafeStore(t, func(t *testing.T, constructor func(selector func(counter) int, values ...counter) kset.KeyValueSet[int, counter]) {
+		set := constructor(counterID)
+
+		// Replaying the events in the order they are received must rebuild the set.
+		mirror := map[int]counter{}
+		set.Observe(func(event counterEvent) {
+			switch event.Kind {
+			case kset.EventInsert:
+				assert.NotContains(t, mirror, event.Key)
+				mirror[event.Key] = event.Value
+			case kset.EventReplace:
+				assert.Equal(t, mirror[event.Key], event.Old)
+				mirror[event.Key] = event.Value
+			case kset.EventDelete:
+				assert.Equal(t, mirror[event.Key], event.Old)
+				delete(mirror, event.Key)
+			case kset.EventClear:
+				clear(mirror)
+			}
+		})
+
+		var wg sync.WaitGroup
+		for worker := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range operations {
+					key := (worker + i) % 16
+					switch i % 5 {
+					case 0, 1:
+						set.Append(counter{key, i}, counter{key + 1, i})
+					case 2:
+						set.RemoveKeys(key)
+					case 3:
+						set.Compute(key, func(old counter, ok bool) (counter, bool) {
+							return counter{key, old.Hits + 1}, true
+						})
+					case 4:
+						set.Pop()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, set.Map(), mirror)
+	})
+}
@@ -0,0 +1,203 @@
+package kset
+
+import "iter"
+
+// OrderedKeySet is a KeySet that keeps its keys sorted.
+// Besides the KeySet operations, it allows navigating through the keys in order.
+// Keys, Slice and Pop follow the ascending order of the keys.
+// Clone, Union, Intersect, Difference, DifferenceKeys and SymmetricDifference keep the signatures of KeySet,
+// as Go has no covariant return types and an OrderedKeySet must remain usable as a KeySet,
+// but the sets they return always implement OrderedKeySet.
+// Likewise, Freeze keeps its signature, but the view it returns always implements ReadOnlyOrderedKeySet.
+// Example:
+//
+//	s := kset.TreeMapKey(1, 2, 3)
+//	union := s.Union(kset.HashMapKey(4)).(kset.OrderedKeySet[int])
+//	key, ok := union.Max() // key is 4, ok is true
+type OrderedKeySet[Key any] interface {
+	KeySet[Key]
+	ReadOnlyOrderedKeySet[Key]
+}
+
+// ReadOnlyOrderedKeySet is the subset of OrderedKeySet that does not mutate it.
+// The read-only view of an ordered set, returned by Freeze, can be asserted to it to keep navigating through its keys.
+// Example:
+//
+//	view := kset.TreeMapKey(3, 1, 2).Freeze().(kset.ReadOnlyOrderedKeySet[int])
+//	key, ok := view.Min() // key is 1, ok is true
+type ReadOnlyOrderedKeySet[Key any] interface {
+	ReadOnlyKeySet[Key]
+
+	// Min returns the smallest key of the set.
+	// It returns false if the set is empty.
+	// Example:
+	//  s := kset.TreeMapKey(3, 1, 2)
+	//  key, ok := s.Min() // key is 1, ok is true
+	Min() (Key, bool)
+
+	// Max returns the greatest key of the set.
+	// It returns false if the set is empty.
+	// Example:
+	//  s := kset.TreeMapKey(3, 1, 2)
+	//  key, ok := s.Max() // key is 3, ok is true
+	Max() (Key, bool)
+
+	// Floor returns the greatest key less than or equal to the given key.
+	// Example:
+	//  s := kset.TreeMapKey(1, 3, 5)
+	//  key, ok := s.Floor(4) // key is 3, ok is true
+	//  key, ok = s.Floor(0) // key is 0, ok is false
+	Floor(key Key) (Key, bool)
+
+	// Ceiling returns the smallest key greater than or equal to the given key.
+	// Example:
+	//  s := kset.TreeMapKey(1, 3, 5)
+	//  key, ok := s.Ceiling(4) // key is 5, ok is true
+	//  key, ok = s.Ceiling(6) // key is 0, ok is false
+	Ceiling(key Key) (Key, bool)
+
+	// Lower returns the greatest key strictly less than the given key.
+	// Example:
+	//  s := kset.TreeMapKey(1, 3, 5)
+	//  key, ok := s.Lower(3) // key is 1, ok is true
+	Lower(key Key) (Key, bool)
+
+	// Higher returns the smallest key strictly greater than the given key.
+	// Example:
+	//  s := kset.TreeMapKey(1, 3, 5)
+	//  key, ok := s.Higher(3) // key is 5, ok is true
+	Higher(key Key) (Key, bool)
+
+	// Rank returns the number of keys in the set strictly less than the given key.
+	// Example:
+	//  s := kset.TreeMapKey(10, 20, 30)
+	//  rank := s.Rank(25) // rank is 2
+	Rank(key Key) int
+
+	// At returns the i-th smallest key of the set, starting from 0.
+	// It returns false if i is out of bounds.
+	// Example:
+	//  s := kset.TreeMapKey(10, 20, 30)
+	//  key, ok := s.At(1) // key is 20, ok is true
+	//  key, ok = s.At(3) // key is 0, ok is false
+	At(i int) (Key, bool)
+
+	// Range iterates in ascending order through the keys in the interval [from, to).
+	// Example:
+	//  s := kset.TreeMapKey(1, 2, 3, 4, 5)
+	//  s.Range(2, 4) // returns iter[2, 3]
+	Range(from, to Key) iter.Seq[Key]
+
+	// Backward iterates through all keys in descending order.
+	// Example:
+	//  s := kset.TreeMapKey(1, 2, 3)
+	//  s.Backward() // returns iter[3, 2, 1]
+	Backward() iter.Seq[Key]
+}
+
+// orderedKeySet is an implementation of OrderedKeySet.
+// It extends keySet, making sure derived sets are also ordered.
+type orderedKeySet[Key any, Store orderedStorage[Key, empty]] struct {
+	*keySet[Key, Store]
+}
+
+// wrap converts a set derived from the embedded keySet back into an ordered set.
+func (k *orderedKeySet[Key, Store]) wrap(set KeySet[Key]) KeySet[Key] {
+	return &orderedKeySet[Key, Store]{
+		keySet: set.(*keySet[Key, Store]),
+	}
+}
+
+// Clone creates a copy of the set.
+func (k *orderedKeySet[Key, Store]) Clone() KeySet[Key] {
+	return k.wrap(k.keySet.Clone())
+}
+
+// Difference returns a new set with keys in this set but not in the other.
+func (k *orderedKeySet[Key, Store]) Difference(other ReadOnlySet[Key]) KeySet[Key] {
+	return k.wrap(k.keySet.Difference(other))
+}
+
+// DifferenceKeys returns a new set, not containing the given keys.
+func (k *orderedKeySet[Key, Store]) DifferenceKeys(keys ...Key) KeySet[Key] {
+	return k.wrap(k.keySet.DifferenceKeys(keys...))
+}
+
+// Intersect returns a new set with keys common to both this set and the other.
+func (k *orderedKeySet[Key, Store]) Intersect(other ReadOnlySet[Key]) KeySet[Key] {
+	return k.wrap(k.keySet.Intersect(other))
+}
+
+// SymmetricDifference returns a new set with keys in either this set or the other, but not both.
+func (k *orderedKeySet[Key, Store]) SymmetricDifference(other ReadOnlyKeySet[Key]) KeySet[Key] {
+	return k.wrap(k.keySet.SymmetricDifference(other))
+}
+
+// Union returns a new set with all keys from both this set and the other.
+func (k *orderedKeySet[Key, Store]) Union(other ReadOnlyKeySet[Key]) KeySet[Key] {
+	return k.wrap(k.keySet.Union(other))
+}
+
+// Min returns the smallest key of the set.
+func (k *orderedKeySet[Key, Store]) Min() (Key, bool) {
+	key, _, ok := k.store.Min()
+	return key, ok
+}
+
+// Max returns the greatest key of the set.
+func (k *orderedKeySet[Key, Store]) Max() (Key, bool) {
+	key, _, ok := k.store.Max()
+	return key, ok
+}
+
+// Floor returns the greatest key less than or equal to the given key.
+func (k *orderedKeySet[Key, Store]) Floor(key Key) (Key, bool) {
+	key, _, ok := k.store.Floor(key)
+	return key, ok
+}
+
+// Ceiling returns the smallest key greater than or equal to the given key.
+func (k *orderedKeySet[Key, Store]) Ceiling(key Key) (Key, bool) {
+	key, _, ok := k.store.Ceiling(key)
+	return key, ok
+}
+
+// Lower returns the greatest key strictly less than the given key.
+func (k *orderedKeySet[Key, Store]) Lower(key Key) (Key, bool) {
+	key, _, ok := k.store.Lower(key)
+	return key, ok
+}
+
+// Higher returns the smallest key strictly greater than the given key.
+func (k *orderedKeySet[Key, Store]) Higher(key Key) (Key, bool) {
+	key, _, ok := k.store.Higher(key)
+	return key, ok
+}
+
+// Rank returns the number of keys strictly less than the given key.
+func (k *orderedKeySet[Key, Store]) Rank(key Key) int {
+	return k.store.Rank(key)
+}
+
+// At returns the i-th smallest key of the set.
+func (k *orderedKeySet[Key, Store]) At(i int) (Key, bool) {
+	key, _, ok := k.store.At(i)
+	return key, ok
+}
+
+// Range iterates in ascending order through the keys in the interval [from, to).
+func (k *orderedKeySet[Key, Store]) Range(from, to Key) iter.Seq[Key] {
+	return keysOf(k.store.Range(from, to))
+}
+
+// Backward iterates through all keys in descending order.
+func (k *orderedKeySet[Key, Store]) Backward() iter.Seq[Key] {
+	return keysOf(k.store.Backward())
+}
+
+func (k *orderedKeySet[Key, Store]) sortedKeys() iter.Seq[Key] {
+	return k.storeKeys()
+}
+
+// Ensure orderedKeySet implements OrderedKeySet at compile time.
+var _ OrderedKeySet[string] = &orderedKeySet[string, *treeMapStore[string, empty]]{}
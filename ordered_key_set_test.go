@@ -0,0 +1,329 @@
+package kset_test
+
+import (
+	"cmp"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/constraints"
+)
+
+func forEachOrderedStoreK[K constraints.Ordered](t *testing.T, f func(t *testing.T, constructor func(keys ...K) kset.OrderedKeySet[K])) {
+	type tc struct {
+		name string
+		f    func(keys ...K) kset.OrderedKeySet[K]
+	}
+
+	stores := []tc{
+		{name: "TreeMapKey", f: kset.TreeMapKey[K]},
+		{name: "UnsafeTreeMapKey", f: kset.UnsafeTreeMapKey[K]},
+		{name: "SortedSliceKey", f: kset.SortedSliceKey[K]},
+		{name: "PersistentTreeMapKey", f: kset.PersistentTreeMapKey[K]},
+		{name: "TreeMapKeyFunc", f: func(keys ...K) kset.OrderedKeySet[K] {
+			return kset.TreeMapKeyFunc(cmp.Compare[K], keys...)
+		}},
+		{name: "UnsafeTreeMapKeyFunc", f: func(keys ...K) kset.OrderedKeySet[K] {
+			return kset.UnsafeTreeMapKeyFunc(cmp.Compare[K], keys...)
+		}},
+	}
+
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			f(t, tc.f)
+		})
+	}
+}
+
+func Test_OrderedKeySet_MinMax(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		t.Run("empty", func(t *testing.T) {
+			set := constructor()
+
+			_, ok := set.Min()
+			assert.False(t, ok)
+			_, ok = set.Max()
+			assert.False(t, ok)
+		})
+
+		t.Run("not empty", func(t *testing.T) {
+			set := constructor(3, 1, 2)
+
+			key, ok := set.Min()
+			assert.True(t, ok)
+			assert.Equal(t, 1, key)
+
+			key, ok = set.Max()
+			assert.True(t, ok)
+			assert.Equal(t, 3, key)
+		})
+	})
+}
+
+func Test_OrderedKeySet_Navigation(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(1, 3, 5)
+
+		type result struct {
+			key int
+			ok  bool
+		}
+
+		testCases := []struct {
+			name     string
+			f        func(int) (int, bool)
+			input    int
+			expected result
+		}{
+			{name: "floor exact", f: set.Floor, input: 3, expected: result{3, true}},
+			{name: "floor between", f: set.Floor, input: 4, expected: result{3, true}},
+			{name: "floor before first", f: set.Floor, input: 0, expected: result{0, false}},
+			{name: "floor after last", f: set.Floor, input: 9, expected: result{5, true}},
+			{name: "ceiling exact", f: set.Ceiling, input: 3, expected: result{3, true}},
+			{name: "ceiling between", f: set.Ceiling, input: 4, expected: result{5, true}},
+			{name: "ceiling after last", f: set.Ceiling, input: 6, expected: result{0, false}},
+			{name: "lower exact", f: set.Lower, input: 3, expected: result{1, true}},
+			{name: "lower first", f: set.Lower, input: 1, expected: result{0, false}},
+			{name: "lower after last", f: set.Lower, input: 9, expected: result{5, true}},
+			{name: "higher exact", f: set.Higher, input: 3, expected: result{5, true}},
+			{name: "higher last", f: set.Higher, input: 5, expected: result{0, false}},
+			{name: "higher before first", f: set.Higher, input: 0, expected: result{1, true}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				key, ok := tc.f(tc.input)
+				assert.Equal(t, tc.expected, result{key, ok})
+			})
+		}
+	})
+}
+
+func Test_OrderedKeySet_Range(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(5, 4, 3, 2, 1)
+
+		assert.Equal(t, []int{2, 3}, slices.Collect(set.Range(2, 4)))
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, slices.Collect(set.Range(0, 9)))
+		assert.Empty(t, slices.Collect(set.Range(4, 2)))
+		assert.Empty(t, slices.Collect(set.Range(3, 3)))
+	})
+}
+
+func Test_OrderedKeySet_Range_Large(t *testing.T) {
+	// Enough keys for thread-safe sets to iterate through several chunks.
+	keys := make([]int, 1000)
+	for i := range keys {
+		keys[i] = i * 2
+	}
+
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(keys...)
+		assert.Equal(t, keys[5:900], slices.Collect(set.Range(9, 1800)))
+
+		backward := slices.Collect(set.Backward())
+		slices.Reverse(backward)
+		assert.Equal(t, keys, backward)
+	})
+
+	// The loop body can modify thread-safe sets without deadlocking.
+	for _, set := range []kset.OrderedKeySet[int]{kset.TreeMapKey(keys...), kset.SortedSliceKey(keys...)} {
+		for key := range set.Range(0, 1000) {
+			set.RemoveKeys(key)
+		}
+		for key := range set.Backward() {
+			set.RemoveKeys(key)
+		}
+		assert.True(t, set.IsEmpty())
+	}
+}
+
+func Test_OrderedKeySet_Order(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(3, 1, 2)
+
+		assert.Equal(t, []int{1, 2, 3}, slices.Collect(set.Keys()))
+		assert.Equal(t, []int{3, 2, 1}, slices.Collect(set.Backward()))
+
+		for key := range set.Backward() {
+			assert.Equal(t, 3, key)
+			break
+		}
+	})
+}
+
+func Test_OrderedKeySet_Derived(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set1 := constructor(1, 2, 3)
+		set2 := constructor(3, 4)
+
+		derived := []kset.KeySet[int]{
+			set1.Clone(),
+			set1.Difference(set2),
+			set1.DifferenceKeys(1),
+			set1.Intersect(set2),
+			set1.SymmetricDifference(set2),
+			set1.Union(set2),
+		}
+
+		for _, set := range derived {
+			_, ok := set.(kset.OrderedKeySet[int])
+			assert.True(t, ok)
+		}
+	})
+}
+
+func Test_OrderedKeySet_RankAt(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(30, 10, 20)
+
+		assert.Equal(t, 0, set.Rank(5))
+		assert.Equal(t, 0, set.Rank(10))
+		assert.Equal(t, 2, set.Rank(25))
+		assert.Equal(t, 3, set.Rank(99))
+
+		for i, expected := range []int{10, 20, 30} {
+			key, ok := set.At(i)
+			assert.True(t, ok)
+			assert.Equal(t, expected, key)
+		}
+
+		_, ok := set.At(-1)
+		assert.False(t, ok)
+		_, ok = set.At(3)
+		assert.False(t, ok)
+	})
+}
+
+func Test_OrderedKeySet_Random(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		rng := rand.New(rand.NewPCG(1, 2))
+		set := constructor()
+		expected := map[int]struct{}{}
+
+		for range 5000 {
+			key := rng.IntN(500)
+			if rng.IntN(3) == 0 {
+				set.RemoveKeys(key)
+				delete(expected, key)
+			} else {
+				set.Append(key)
+				expected[key] = struct{}{}
+			}
+		}
+
+		sorted := slices.Sorted(maps.Keys(expected))
+		require.Equal(t, sorted, slices.Collect(set.Keys()))
+
+		for i, key := range sorted {
+			assert.Equal(t, i, set.Rank(key))
+			got, ok := set.At(i)
+			assert.True(t, ok)
+			assert.Equal(t, key, got)
+		}
+	})
+}
+
+func Test_OrderedKeySet_Func(t *testing.T) {
+	type version struct {
+		major, minor int
+	}
+
+	compare := func(a, b version) int {
+		if c := cmp.Compare(a.major, b.major); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.minor, b.minor)
+	}
+
+	t.Run("struct keys", func(t *testing.T) {
+		set := kset.TreeMapKeyFunc(compare, version{2, 0}, version{1, 10}, version{1, 2}, version{1, 2})
+
+		assert.Equal(t, []version{{1, 2}, {1, 10}, {2, 0}}, slices.Collect(set.Keys()))
+
+		key, ok := set.Floor(version{1, 99})
+		assert.True(t, ok)
+		assert.Equal(t, version{1, 10}, key)
+		assert.Equal(t, 2, set.Rank(version{2, 0}))
+	})
+
+	t.Run("time keys", func(t *testing.T) {
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		set := kset.UnsafeTreeMapKeyFunc(time.Time.Compare, base.Add(time.Hour), base, base.Add(time.Minute))
+
+		first, ok := set.Min()
+		assert.True(t, ok)
+		assert.Equal(t, base, first)
+
+		assert.Equal(t, []time.Time{base.Add(time.Minute)}, slices.Collect(set.Range(base.Add(time.Second), base.Add(time.Hour))))
+	})
+
+	t.Run("reversed order", func(t *testing.T) {
+		set := kset.TreeMapKeyFunc(func(a, b int) int { return cmp.Compare(b, a) }, 1, 2, 3)
+
+		assert.Equal(t, []int{3, 2, 1}, slices.Collect(set.Keys()))
+		assert.Equal(t, []int{1, 2, 3}, slices.Collect(set.Backward()))
+	})
+}
+
+func Test_OrderedKeySet_Merge(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	randomKeys := func(n int) []int {
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = rng.IntN(n * 2)
+		}
+		return keys
+	}
+
+	aKeys, bKeys := randomKeys(300), randomKeys(200)
+	expectedA, expectedB := kset.HashMapKey(aKeys...), kset.HashMapKey(bKeys...)
+
+	operands := map[string]kset.KeySet[int]{
+		"same order":      kset.UnsafeTreeMapKey(bKeys...),
+		"different order": kset.TreeMapKeyFunc(func(a, b int) int { return cmp.Compare(b, a) }, bKeys...),
+		"not ordered":     kset.HashMapKey(bKeys...),
+	}
+
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		a := constructor(aKeys...)
+
+		for name, b := range operands {
+			t.Run(name, func(t *testing.T) {
+				assert.ElementsMatch(t, expectedA.Union(expectedB).Slice(), a.Union(b).Slice())
+				assert.ElementsMatch(t, expectedA.Intersect(expectedB).Slice(), a.Intersect(b).Slice())
+				assert.ElementsMatch(t, expectedA.Difference(expectedB).Slice(), a.Difference(b).Slice())
+				assert.ElementsMatch(t, expectedA.SymmetricDifference(expectedB).Slice(), a.SymmetricDifference(b).Slice())
+
+				union := a.Union(b).(kset.OrderedKeySet[int])
+				assert.True(t, slices.IsSorted(union.Slice()))
+				assert.Equal(t, union.Len()-1, union.Rank(slices.Max(union.Slice())))
+			})
+		}
+
+		// The operands must not be modified.
+		assert.ElementsMatch(t, expectedA.Slice(), a.Slice())
+	})
+}
+
+func Test_OrderedKeySet_Batch(t *testing.T) {
+	forEachOrderedStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.OrderedKeySet[int]) {
+		set := constructor(5, 1, 9)
+
+		count := set.Append(7, 3, 9, 3, 0, 11, 1)
+		assert.Equal(t, 4, count)
+		assert.Equal(t, []int{0, 1, 3, 5, 7, 9, 11}, slices.Collect(set.Keys()))
+
+		set.RemoveKeys(11, 4, 0, 5, 5, 7)
+		assert.Equal(t, []int{1, 3, 9}, slices.Collect(set.Keys()))
+
+		set.RemoveKeys(1, 3, 9)
+		assert.True(t, set.IsEmpty())
+	})
+}
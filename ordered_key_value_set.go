@@ -0,0 +1,191 @@
+package kset
+
+import "iter"
+
+// OrderedKeyValueSet is a KeyValueSet that keeps its elements sorted by key.
+// Besides the KeyValueSet operations, it allows navigating through the elements in key order.
+// KeyValues, Keys, Slice and Pop follow the ascending order of the keys.
+// Clone, Union, UnionWith, Intersect, Difference, DifferenceKeys and SymmetricDifference keep the signatures of KeyValueSet,
+// as Go has no covariant return types and an OrderedKeyValueSet must remain usable as a KeyValueSet,
+// but the sets they return always implement OrderedKeyValueSet.
+// Likewise, Freeze keeps its signature, but the view it returns always implements ReadOnlyOrderedKeyValueSet.
+// Example:
+//
+//	s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3)
+//	union := s.Union(kset.HashMapKeyValue(func(v int) int { return v }, 4)).(kset.OrderedKeyValueSet[int, int])
+//	value, ok := union.Max() // value is 4, ok is true
+type OrderedKeyValueSet[Key comparable, Value any] interface {
+	KeyValueSet[Key, Value]
+	ReadOnlyOrderedKeyValueSet[Key, Value]
+}
+
+// ReadOnlyOrderedKeyValueSet is the subset of OrderedKeyValueSet that does not mutate it.
+// The read-only view of an ordered set, returned by Freeze, can be asserted to it to keep navigating through its elements.
+// Example:
+//
+//	view := kset.TreeMapKeyValue(func(v int) int { return v }, 3, 1, 2).Freeze().(kset.ReadOnlyOrderedKeyValueSet[int, int])
+//	value, ok := view.Min() // value is 1, ok is true
+type ReadOnlyOrderedKeyValueSet[Key comparable, Value any] interface {
+	ReadOnlyKeyValueSet[Key, Value]
+
+	// Min returns the element with the smallest key of the set.
+	// It returns false if the set is empty.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 3, 1, 2)
+	//  value, ok := s.Min() // value is 1, ok is true
+	Min() (Value, bool)
+
+	// Max returns the element with the greatest key of the set.
+	// It returns false if the set is empty.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 3, 1, 2)
+	//  value, ok := s.Max() // value is 3, ok is true
+	Max() (Value, bool)
+
+	// Floor returns the element with the greatest key less than or equal to the given key.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 3, 5)
+	//  value, ok := s.Floor(4) // value is 3, ok is true
+	Floor(key Key) (Value, bool)
+
+	// Ceiling returns the element with the smallest key greater than or equal to the given key.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 3, 5)
+	//  value, ok := s.Ceiling(4) // value is 5, ok is true
+	Ceiling(key Key) (Value, bool)
+
+	// Lower returns the element with the greatest key strictly less than the given key.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 3, 5)
+	//  value, ok := s.Lower(3) // value is 1, ok is true
+	Lower(key Key) (Value, bool)
+
+	// Higher returns the element with the smallest key strictly greater than the given key.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 3, 5)
+	//  value, ok := s.Higher(3) // value is 5, ok is true
+	Higher(key Key) (Value, bool)
+
+	// Rank returns the number of keys in the set strictly less than the given key.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 10, 20, 30)
+	//  rank := s.Rank(25) // rank is 2
+	Rank(key Key) int
+
+	// At returns the element with the i-th smallest key of the set, starting from 0.
+	// It returns false if i is out of bounds.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 10, 20, 30)
+	//  value, ok := s.At(1) // value is 20, ok is true
+	At(i int) (Value, bool)
+
+	// Range iterates in ascending key order through the elements with keys in the interval [from, to).
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3, 4, 5)
+	//  s.Range(2, 4) // returns iter[2:2, 3:3]
+	Range(from, to Key) iter.Seq2[Key, Value]
+
+	// Backward iterates through all elements in descending key order.
+	// Example:
+	//  s := kset.TreeMapKeyValue(func(v int) int { return v }, 1, 2, 3)
+	//  s.Backward() // returns iter[3:3, 2:2, 1:1]
+	Backward() iter.Seq2[Key, Value]
+}
+
+// orderedKeyValueSet is an implementation of OrderedKeyValueSet.
+// It extends keyValueSet, making sure derived sets are also ordered.
+type orderedKeyValueSet[Key comparable, Value any, Store orderedStorage[Key, Value]] struct {
+	*keyValueSet[Key, Value, Store]
+}
+
+// wrap converts a set derived from the embedded keyValueSet back into an ordered set.
+func (k *orderedKeyValueSet[Key, Value, Store]) wrap(set KeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+	return &orderedKeyValueSet[Key, Value, Store]{
+		keyValueSet: set.(*keyValueSet[Key, Value, Store]),
+	}
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Clone() KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.Clone())
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Difference(other ReadOnlySet[Key]) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.Difference(other))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) DifferenceKeys(keys ...Key) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.DifferenceKeys(keys...))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Intersect(other ReadOnlySet[Key]) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.Intersect(other))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) SymmetricDifference(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.SymmetricDifference(other))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Union(other ReadOnlyKeyValueSet[Key, Value]) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.Union(other))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) UnionWith(other ReadOnlyKeyValueSet[Key, Value], merge func(key Key, a, b Value) Value) KeyValueSet[Key, Value] {
+	return k.wrap(k.keyValueSet.UnionWith(other, merge))
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Min() (Value, bool) {
+	_, value, ok := k.store.Min()
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Max() (Value, bool) {
+	_, value, ok := k.store.Max()
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Floor(key Key) (Value, bool) {
+	_, value, ok := k.store.Floor(key)
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Ceiling(key Key) (Value, bool) {
+	_, value, ok := k.store.Ceiling(key)
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Lower(key Key) (Value, bool) {
+	_, value, ok := k.store.Lower(key)
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Higher(key Key) (Value, bool) {
+	_, value, ok := k.store.Higher(key)
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Rank(key Key) int {
+	return k.store.Rank(key)
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) At(i int) (Value, bool) {
+	_, value, ok := k.store.At(i)
+	return value, ok
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Range(from, to Key) iter.Seq2[Key, Value] {
+	return k.store.Range(from, to)
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) Backward() iter.Seq2[Key, Value] {
+	return k.store.Backward()
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) sortedKeys() iter.Seq[Key] {
+	return k.storeKeys()
+}
+
+func (k *orderedKeyValueSet[Key, Value, Store]) sortedEntries() iter.Seq2[Key, Value] {
+	return k.storeEntries()
+}
+
+var _ OrderedKeyValueSet[string, string] = &orderedKeyValueSet[string, string, *treeMapStore[string, string]]{}
@@ -0,0 +1,182 @@
+package kset_test
+
+import (
+	"cmp"
+	"maps"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/constraints"
+)
+
+func forEachOrderedStore[K constraints.Ordered, V any](t *testing.T, f func(t *testing.T, constructor func(selector func(V) K, values ...V) kset.OrderedKeyValueSet[K, V])) {
+	type tc struct {
+		name string
+		f    func(selector func(V) K, values ...V) kset.OrderedKeyValueSet[K, V]
+	}
+
+	stores := []tc{
+		{name: "TreeMapKeyValue", f: kset.TreeMapKeyValue[K, V]},
+		{name: "UnsafeTreeMapKeyValue", f: kset.UnsafeTreeMapKeyValue[K, V]},
+		{name: "SortedSliceKeyValue", f: kset.SortedSliceKeyValue[K, V]},
+		{name: "PersistentTreeMapKeyValue", f: kset.PersistentTreeMapKeyValue[K, V]},
+		{name: "TreeMapKeyValueFunc", f: func(selector func(V) K, values ...V) kset.OrderedKeyValueSet[K, V] {
+			return kset.TreeMapKeyValueFunc(cmp.Compare[K], selector, values...)
+		}},
+		{name: "UnsafeTreeMapKeyValueFunc", f: func(selector func(V) K, values ...V) kset.OrderedKeyValueSet[K, V] {
+			return kset.UnsafeTreeMapKeyValueFunc(cmp.Compare[K], selector, values...)
+		}},
+	}
+
+	for _, tc := range stores {
+		t.Run(tc.name, func(t *testing.T) {
+			f(t, tc.f)
+		})
+	}
+}
+
+func Test_OrderedKeyValueSet_MinMax(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		t.Run("empty", func(t *testing.T) {
+			set := constructor(testKeyer)
+
+			_, ok := set.Min()
+			assert.False(t, ok)
+			_, ok = set.Max()
+			assert.False(t, ok)
+		})
+
+		t.Run("not empty", func(t *testing.T) {
+			set := constructor(testKeyer, 3, 1, 2)
+
+			value, ok := set.Min()
+			assert.True(t, ok)
+			assert.Equal(t, 1, value)
+
+			value, ok = set.Max()
+			assert.True(t, ok)
+			assert.Equal(t, 3, value)
+		})
+	})
+}
+
+func Test_OrderedKeyValueSet_Navigation(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		set := constructor(testKeyer, 1, 3, 5)
+
+		value, ok := set.Floor(4)
+		assert.True(t, ok)
+		assert.Equal(t, 3, value)
+
+		value, ok = set.Ceiling(4)
+		assert.True(t, ok)
+		assert.Equal(t, 5, value)
+
+		value, ok = set.Lower(3)
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+
+		value, ok = set.Higher(3)
+		assert.True(t, ok)
+		assert.Equal(t, 5, value)
+
+		_, ok = set.Higher(5)
+		assert.False(t, ok)
+	})
+}
+
+func Test_OrderedKeyValueSet_Range(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		set := constructor(testKeyer, 5, 4, 3, 2, 1)
+
+		assert.Equal(t, map[int]int{2: 2, 3: 3}, maps.Collect(set.Range(2, 4)))
+		assert.Empty(t, maps.Collect(set.Range(4, 2)))
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, set.Slice())
+
+		backward := make([]int, 0, set.Len())
+		for key := range set.Backward() {
+			backward = append(backward, key)
+		}
+		assert.Equal(t, []int{5, 4, 3, 2, 1}, backward)
+	})
+}
+
+func Test_OrderedKeyValueSet_RankAt(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		set := constructor(testKeyer, 30, 10, 20)
+
+		assert.Equal(t, 2, set.Rank(25))
+
+		value, ok := set.At(2)
+		assert.True(t, ok)
+		assert.Equal(t, 30, value)
+
+		_, ok = set.At(3)
+		assert.False(t, ok)
+	})
+}
+
+func Test_OrderedKeyValueSet_Merge(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	selector := func(u user) int { return u.ID }
+
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.OrderedKeyValueSet[int, user]) {
+		a := constructor(selector, user{1, "a1"}, user{2, "a2"}, user{3, "a3"})
+		b := kset.TreeMapKeyValue(selector, user{3, "b3"}, user{4, "b4"})
+
+		assert.Equal(t, []user{{1, "a1"}, {2, "a2"}, {3, "b3"}, {4, "b4"}}, a.Union(b).Slice())
+		keepA := func(_ int, a, _ user) user { return a }
+		union := a.UnionWith(b, keepA)
+		assert.Equal(t, []user{{1, "a1"}, {2, "a2"}, {3, "a3"}, {4, "b4"}}, union.Slice())
+		assert.Implements(t, (*kset.OrderedKeyValueSet[int, user])(nil), union)
+		assert.Equal(t, []user{{3, "a3"}}, a.Intersect(b).Slice())
+		assert.Equal(t, []user{{1, "a1"}, {2, "a2"}}, a.Difference(b).Slice())
+		assert.Equal(t, []user{{1, "a1"}, {2, "a2"}, {4, "b4"}}, a.SymmetricDifference(b).Slice())
+		assert.Equal(t, []user{{1, "a1"}, {2, "a2"}}, a.Difference(kset.TreeMapKey(3, 4)).Slice())
+	})
+}
+
+func Test_OrderedKeyValueSet_Derived(t *testing.T) {
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(int) int, values ...int) kset.OrderedKeyValueSet[int, int]) {
+		set1 := constructor(testKeyer, 1, 2, 3)
+		set2 := constructor(testKeyer, 3, 4)
+
+		derived := []kset.KeyValueSet[int, int]{
+			set1.Clone(),
+			set1.Difference(set2),
+			set1.DifferenceKeys(1),
+			set1.Intersect(set2),
+			set1.SymmetricDifference(set2),
+			set1.Union(set2),
+			set1.UnionWith(set2, func(_, a, _ int) int { return a }),
+		}
+
+		for _, set := range derived {
+			_, ok := set.(kset.OrderedKeyValueSet[int, int])
+			assert.True(t, ok)
+		}
+	})
+}
+
+func Test_OrderedKeyValueSet_Batch(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	selector := func(u user) int { return u.ID }
+
+	forEachOrderedStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.OrderedKeyValueSet[int, user]) {
+		set := constructor(selector, user{2, "a"}, user{1, "b"}, user{2, "c"})
+		assert.Equal(t, []user{{1, "b"}, {2, "c"}}, set.Slice())
+
+		count := set.Append(user{3, "d"}, user{1, "e"}, user{3, "f"})
+		assert.Equal(t, 1, count)
+		assert.Equal(t, []user{{1, "e"}, {2, "c"}, {3, "f"}}, set.Slice())
+	})
+}
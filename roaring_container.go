@@ -0,0 +1,379 @@
+package kset
+
+import (
+	"cmp"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// arrayMaxSize is the greatest cardinality stored as a sorted array, above it a bitmap is smaller.
+	arrayMaxSize = 4096
+	// bitmapWords is the number of words needed to represent all 2^16 values of a container.
+	bitmapWords = 1 << 16 / wordSize
+	// runMaxSize is the greatest number of runs stored before a bitmap is smaller.
+	runMaxSize = 2047
+)
+
+// container stores the lower 16 bits of all keys sharing the same upper 16 bits.
+// Mutations return the resulting container, as they might change its representation.
+type container interface {
+	contains(x uint16) bool
+	// add inserts x, returning true if it was not present.
+	add(x uint16) (container, bool)
+	// remove deletes x, returning true if it was present.
+	remove(x uint16) (container, bool)
+	cardinality() int
+	// runCount returns the number of runs of consecutive values.
+	runCount() int
+	// all iterates through the values in ascending order.
+	all() iter.Seq[uint16]
+	clone() container
+}
+
+// arrayContainer is a sorted array of values, used for sparse containers.
+type arrayContainer struct {
+	values []uint16
+}
+
+// bitmapContainer has one bit for each possible value, used for dense containers.
+type bitmapContainer struct {
+	words []uint64
+	card  int
+}
+
+// runContainer is a sorted list of disjoint intervals, used for containers with consecutive values.
+type runContainer struct {
+	runs []roaringRun
+	card int
+}
+
+// roaringRun is the interval [start, last].
+type roaringRun struct {
+	start, last uint16
+}
+
+func (a *arrayContainer) contains(x uint16) bool {
+	_, found := slices.BinarySearch(a.values, x)
+	return found
+}
+
+func (a *arrayContainer) add(x uint16) (container, bool) {
+	i, found := slices.BinarySearch(a.values, x)
+	if found {
+		return a, false
+	}
+	if len(a.values) == arrayMaxSize {
+		return toBitmap(a).add(x)
+	}
+	a.values = slices.Insert(a.values, i, x)
+	return a, true
+}
+
+func (a *arrayContainer) remove(x uint16) (container, bool) {
+	i, found := slices.BinarySearch(a.values, x)
+	if !found {
+		return a, false
+	}
+	a.values = slices.Delete(a.values, i, i+1)
+	return a, true
+}
+
+func (a *arrayContainer) cardinality() int {
+	return len(a.values)
+}
+
+func (a *arrayContainer) runCount() int {
+	count := 0
+	for i, value := range a.values {
+		if i == 0 || value != a.values[i-1]+1 {
+			count++
+		}
+	}
+	return count
+}
+
+func (a *arrayContainer) all() iter.Seq[uint16] {
+	return slices.Values(a.values)
+}
+
+func (a *arrayContainer) clone() container {
+	return &arrayContainer{
+		values: slices.Clone(a.values),
+	}
+}
+
+func (b *bitmapContainer) contains(x uint16) bool {
+	return b.words[x/wordSize]&(1<<(x%wordSize)) != 0
+}
+
+func (b *bitmapContainer) add(x uint16) (container, bool) {
+	mask := uint64(1) << (x % wordSize)
+	if b.words[x/wordSize]&mask != 0 {
+		return b, false
+	}
+	b.words[x/wordSize] |= mask
+	b.card++
+	return b, true
+}
+
+func (b *bitmapContainer) remove(x uint16) (container, bool) {
+	mask := uint64(1) << (x % wordSize)
+	if b.words[x/wordSize]&mask == 0 {
+		return b, false
+	}
+	b.words[x/wordSize] &^= mask
+	b.card--
+	if b.card <= arrayMaxSize {
+		return toArray(b), true
+	}
+	return b, true
+}
+
+func (b *bitmapContainer) cardinality() int {
+	return b.card
+}
+
+// runCount counts the bits set whose previous bit is not set, each one starting a run.
+func (b *bitmapContainer) runCount() int {
+	count := 0
+	var carry uint64
+	for _, word := range b.words {
+		count += bits.OnesCount64(word &^ (word<<1 | carry))
+		carry = word >> (wordSize - 1)
+	}
+	return count
+}
+
+func (b *bitmapContainer) all() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		for i, word := range b.words {
+			for word != 0 {
+				if !yield(uint16(i*wordSize + bits.TrailingZeros64(word))) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+func (b *bitmapContainer) clone() container {
+	return &bitmapContainer{
+		words: slices.Clone(b.words),
+		card:  b.card,
+	}
+}
+
+// find returns the position of the last run starting at or before x, or -1 if there is none.
+func (r *runContainer) find(x uint16) int {
+	i, _ := slices.BinarySearchFunc(r.runs, x, func(run roaringRun, x uint16) int {
+		if run.start <= x {
+			return -1
+		}
+		return 1
+	})
+	return i - 1
+}
+
+func (r *runContainer) contains(x uint16) bool {
+	i := r.find(x)
+	return i >= 0 && r.runs[i].last >= x
+}
+
+func (r *runContainer) add(x uint16) (container, bool) {
+	i := r.find(x)
+	if i >= 0 && r.runs[i].last >= x {
+		return r, false
+	}
+
+	extendsPrevious := i >= 0 && int(r.runs[i].last)+1 == int(x)
+	extendsNext := i+1 < len(r.runs) && int(r.runs[i+1].start) == int(x)+1
+
+	switch {
+	case extendsPrevious && extendsNext:
+		r.runs[i].last = r.runs[i+1].last
+		r.runs = slices.Delete(r.runs, i+1, i+2)
+	case extendsPrevious:
+		r.runs[i].last = x
+	case extendsNext:
+		r.runs[i+1].start = x
+	default:
+		r.runs = slices.Insert(r.runs, i+1, roaringRun{start: x, last: x})
+	}
+	r.card++
+
+	if len(r.runs) > runMaxSize {
+		return expand(r), true
+	}
+	return r, true
+}
+
+func (r *runContainer) remove(x uint16) (container, bool) {
+	i := r.find(x)
+	if i < 0 || r.runs[i].last < x {
+		return r, false
+	}
+
+	switch run := r.runs[i]; {
+	case run.start == run.last:
+		r.runs = slices.Delete(r.runs, i, i+1)
+	case run.start == x:
+		r.runs[i].start++
+	case run.last == x:
+		r.runs[i].last--
+	default:
+		r.runs[i].last = x - 1
+		r.runs = slices.Insert(r.runs, i+1, roaringRun{start: x + 1, last: run.last})
+	}
+	r.card--
+
+	if len(r.runs) > runMaxSize {
+		return expand(r), true
+	}
+	return r, true
+}
+
+func (r *runContainer) cardinality() int {
+	return r.card
+}
+
+func (r *runContainer) runCount() int {
+	return len(r.runs)
+}
+
+func (r *runContainer) all() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		for _, run := range r.runs {
+			for x := int(run.start); x <= int(run.last); x++ {
+				if !yield(uint16(x)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *runContainer) clone() container {
+	return &runContainer{
+		runs: slices.Clone(r.runs),
+		card: r.card,
+	}
+}
+
+func toArray(c container) *arrayContainer {
+	if array, ok := c.(*arrayContainer); ok {
+		return array
+	}
+	return &arrayContainer{
+		values: bufferedCollect(c.all(), c.cardinality()),
+	}
+}
+
+func toBitmap(c container) *bitmapContainer {
+	if bitmap, ok := c.(*bitmapContainer); ok {
+		return bitmap
+	}
+	bitmap := &bitmapContainer{
+		words: make([]uint64, bitmapWords),
+		card:  c.cardinality(),
+	}
+	for x := range c.all() {
+		bitmap.words[x/wordSize] |= 1 << (x % wordSize)
+	}
+	return bitmap
+}
+
+func toRuns(c container) *runContainer {
+	if runs, ok := c.(*runContainer); ok {
+		return runs
+	}
+	result := &runContainer{
+		runs: make([]roaringRun, 0, c.runCount()),
+		card: c.cardinality(),
+	}
+	for x := range c.all() {
+		if n := len(result.runs); n > 0 && int(result.runs[n-1].last)+1 == int(x) {
+			result.runs[n-1].last = x
+			continue
+		}
+		result.runs = append(result.runs, roaringRun{start: x, last: x})
+	}
+	return result
+}
+
+// expand converts the container to an array or a bitmap, depending on its cardinality.
+func expand(c container) container {
+	if c.cardinality() <= arrayMaxSize {
+		return toArray(c)
+	}
+	return toBitmap(c)
+}
+
+// optimize converts the container to its smallest representation, or returns nil if it is empty.
+func optimize(c container) container {
+	card := c.cardinality()
+	if card == 0 {
+		return nil
+	}
+
+	runSize := 2 + 4*c.runCount()
+	if card <= arrayMaxSize {
+		if runSize < 2*card {
+			return toRuns(c)
+		}
+		return toArray(c)
+	}
+	if runSize < 8*bitmapWords {
+		return toRuns(c)
+	}
+	return toBitmap(c)
+}
+
+// combineContainers computes the operation between two containers, without modifying them.
+// It returns nil if the result is empty.
+func combineContainers(a, b container, op mergeOp) container {
+	arrayA, isArrayA := a.(*arrayContainer)
+	arrayB, isArrayB := b.(*arrayContainer)
+
+	switch {
+	case isArrayA && isArrayB:
+		values, _ := mergeSorted[uint16, empty](cmp.Compare[uint16], op, arrayA.values, nil, arrayB.values, nil)
+		return optimize(&arrayContainer{values: values})
+	case isArrayA && (op == mergeIntersection || op == mergeDifference):
+		return optimize(filterContainer(arrayA, b, op == mergeIntersection))
+	case isArrayB && op == mergeIntersection:
+		return optimize(filterContainer(arrayB, a, true))
+	}
+
+	bitmapA, bitmapB := toBitmap(a), toBitmap(b)
+	result := &bitmapContainer{
+		words: make([]uint64, bitmapWords),
+	}
+	for i := range result.words {
+		switch op {
+		case mergeUnion:
+			result.words[i] = bitmapA.words[i] | bitmapB.words[i]
+		case mergeIntersection:
+			result.words[i] = bitmapA.words[i] & bitmapB.words[i]
+		case mergeDifference:
+			result.words[i] = bitmapA.words[i] &^ bitmapB.words[i]
+		case mergeSymmetricDifference:
+			result.words[i] = bitmapA.words[i] ^ bitmapB.words[i]
+		}
+		result.card += bits.OnesCount64(result.words[i])
+	}
+	return optimize(result)
+}
+
+// filterContainer keeps the values of a that are contained in b, or the ones that are not if contained is false.
+func filterContainer(a *arrayContainer, b container, contained bool) *arrayContainer {
+	values := make([]uint16, 0, len(a.values))
+	for _, x := range a.values {
+		if b.contains(x) == contained {
+			values = append(values, x)
+		}
+	}
+	return &arrayContainer{values: values}
+}
@@ -0,0 +1,261 @@
+package kset
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// Constants of the portable roaring format, shared by the roaring implementations of other languages.
+// See https://github.com/RoaringBitmap/RoaringFormatSpec.
+const (
+	roaringCookie            = 12347
+	roaringCookieNoRuns      = 12346
+	roaringNoOffsetThreshold = 4
+)
+
+// ErrInvalidRoaring is returned when decoding data that is not a valid portable roaring bitmap.
+var ErrInvalidRoaring = errors.New("kset: invalid roaring bitmap")
+
+// MarshalRoaring encodes the keys of the set in the portable roaring format,
+// readable by the roaring implementations of other languages.
+// Roaring sets are encoded directly, other sets are converted first.
+// Each container is written in its smallest representation, regardless of how it is stored in memory.
+func MarshalRoaring[Key ~uint32](set ReadOnlyKeySet[Key]) []byte {
+	store, ok := roaringStoreOf(set)
+	if !ok {
+		store = &roaringStore[Key]{}
+		store.UpsertMany(set.Slice(), nil)
+	}
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.appendRoaring(nil)
+}
+
+// UnmarshalRoaring decodes a set from the portable roaring format, returning a RoaringKey set.
+func UnmarshalRoaring[Key ~uint32](data []byte) (KeySet[Key], error) {
+	store, err := decodeRoaring[Key](data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keySet[Key, *roaringStore[Key]]{
+		store: store,
+	}, nil
+}
+
+func roaringStoreOf[Key ~uint32](set ReadOnlyKeySet[Key]) (*roaringStore[Key], bool) {
+	if set, ok := unfreeze[Key](set).(*keySet[Key, *roaringStore[Key]]); ok {
+		return set.store, true
+	}
+	return nil, false
+}
+
+func (r *roaringStore[Key]) appendRoaring(data []byte) []byte {
+	size := len(r.keys)
+	start := len(data)
+
+	containers := make([]container, size)
+	hasRuns := false
+	for i, c := range r.containers {
+		containers[i] = optimize(c)
+		_, isRun := containers[i].(*runContainer)
+		hasRuns = hasRuns || isRun
+	}
+
+	if hasRuns {
+		data = binary.LittleEndian.AppendUint32(data, roaringCookie|uint32(size-1)<<16)
+		runFlags := make([]byte, (size+7)/8)
+		for i, c := range containers {
+			if _, isRun := c.(*runContainer); isRun {
+				runFlags[i/8] |= 1 << (i % 8)
+			}
+		}
+		data = append(data, runFlags...)
+	} else {
+		data = binary.LittleEndian.AppendUint32(data, roaringCookieNoRuns)
+		data = binary.LittleEndian.AppendUint32(data, uint32(size))
+	}
+
+	for i, c := range containers {
+		data = binary.LittleEndian.AppendUint16(data, r.keys[i])
+		data = binary.LittleEndian.AppendUint16(data, uint16(c.cardinality()-1))
+	}
+
+	offsets := -1
+	if !hasRuns || size >= roaringNoOffsetThreshold {
+		offsets = len(data)
+		data = append(data, make([]byte, 4*size)...)
+	}
+
+	for i, c := range containers {
+		if offsets >= 0 {
+			binary.LittleEndian.PutUint32(data[offsets+4*i:], uint32(len(data)-start))
+		}
+
+		switch c := c.(type) {
+		case *runContainer:
+			data = binary.LittleEndian.AppendUint16(data, uint16(len(c.runs)))
+			for _, run := range c.runs {
+				data = binary.LittleEndian.AppendUint16(data, run.start)
+				data = binary.LittleEndian.AppendUint16(data, run.last-run.start)
+			}
+		case *arrayContainer:
+			for _, value := range c.values {
+				data = binary.LittleEndian.AppendUint16(data, value)
+			}
+		case *bitmapContainer:
+			for _, word := range c.words {
+				data = binary.LittleEndian.AppendUint64(data, word)
+			}
+		}
+	}
+
+	return data
+}
+
+// decodeRoaring reads a roaring bitmap, validating that containers are sorted and match their declared cardinality.
+// Offsets are skipped, as containers are read sequentially.
+func decodeRoaring[Key ~uint32](data []byte) (*roaringStore[Key], error) {
+	reader := roaringReader{data: data}
+
+	var size int
+	var runFlags []byte
+
+	switch cookie := reader.uint32(); {
+	case cookie&0xFFFF == roaringCookie:
+		size = int(cookie>>16) + 1
+		runFlags = reader.bytes((size + 7) / 8)
+	case cookie == roaringCookieNoRuns:
+		size = int(reader.uint32())
+		if size > 1<<16 {
+			return nil, ErrInvalidRoaring
+		}
+	default:
+		return nil, ErrInvalidRoaring
+	}
+
+	header := reader.bytes(4 * size)
+	if runFlags == nil || size >= roaringNoOffsetThreshold {
+		reader.bytes(4 * size)
+	}
+	if reader.failed {
+		return nil, ErrInvalidRoaring
+	}
+
+	store := &roaringStore[Key]{
+		keys:       make([]uint16, size),
+		containers: make([]container, size),
+	}
+
+	for i := range size {
+		key := binary.LittleEndian.Uint16(header[4*i:])
+		card := int(binary.LittleEndian.Uint16(header[4*i+2:])) + 1
+		if i > 0 && key <= store.keys[i-1] {
+			return nil, ErrInvalidRoaring
+		}
+
+		var c container
+		switch isRun := runFlags != nil && runFlags[i/8]&(1<<(i%8)) != 0; {
+		case isRun:
+			c = reader.runContainer()
+		case card <= arrayMaxSize:
+			c = reader.arrayContainer(card)
+		default:
+			c = reader.bitmapContainer()
+		}
+		if reader.failed || c == nil || c.cardinality() != card {
+			return nil, ErrInvalidRoaring
+		}
+
+		store.keys[i] = key
+		store.containers[i] = c
+	}
+
+	return store, nil
+}
+
+// roaringReader reads little-endian values, recording any out of bounds or malformed read.
+type roaringReader struct {
+	data   []byte
+	failed bool
+}
+
+func (r *roaringReader) bytes(n int) []byte {
+	if r.failed || n > len(r.data) {
+		r.failed = true
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *roaringReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *roaringReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *roaringReader) arrayContainer(card int) container {
+	b := r.bytes(2 * card)
+	if b == nil {
+		return nil
+	}
+	values := make([]uint16, card)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint16(b[2*i:])
+		if i > 0 && values[i] <= values[i-1] {
+			r.failed = true
+			return nil
+		}
+	}
+	return &arrayContainer{values: values}
+}
+
+func (r *roaringReader) bitmapContainer() container {
+	b := r.bytes(8 * bitmapWords)
+	if b == nil {
+		return nil
+	}
+	bitmap := &bitmapContainer{
+		words: make([]uint64, bitmapWords),
+	}
+	for i := range bitmap.words {
+		bitmap.words[i] = binary.LittleEndian.Uint64(b[8*i:])
+		bitmap.card += bits.OnesCount64(bitmap.words[i])
+	}
+	return bitmap
+}
+
+func (r *roaringReader) runContainer() container {
+	count := int(r.uint16())
+	b := r.bytes(4 * count)
+	if b == nil {
+		return nil
+	}
+	runs := &runContainer{
+		runs: make([]roaringRun, count),
+	}
+	for i := range runs.runs {
+		start := binary.LittleEndian.Uint16(b[4*i:])
+		length := binary.LittleEndian.Uint16(b[4*i+2:])
+		if int(start)+int(length) > 0xFFFF || (i > 0 && start <= runs.runs[i-1].last) {
+			r.failed = true
+			return nil
+		}
+		runs.runs[i] = roaringRun{start: start, last: start + length}
+		runs.card += int(length) + 1
+	}
+	return runs
+}
@@ -6,12 +6,13 @@ import (
 
 // Set defines the interface of the behavior expected from only comparing keys, and not values.
 // This interface is useful for comparing sets that shares the same key type, but not the same value.
+//
+// Decoding rules: every decoder of the package, from UnmarshalJSON, UnmarshalBinary, GobDecode and ReadFrom
+// to UnmarshalBinaryWith, ReadFromWith and the Scan of PostgresArray and JSONArray, replaces all the elements of the set
+// in a single transaction, with the same isolation as Update. A JSON null or a NULL column decodes as an empty set.
+// If the decoder returns an error, such as for invalid data or keys the storage cannot hold, the set is not modified.
 type Set[Key any] interface {
-	// Len returns the number of elements in the set.
-	// Example:
-	//  s := kset.HashMapKey(1, 2)
-	//  length := s.Len() // length is 2
-	Len() int
+	ReadOnlySet[Key]
 
 	// Clear removes all elements from the set.
 	// Example:
@@ -19,6 +20,17 @@ type Set[Key any] interface {
 	//  s.Clear() // s is {}
 	//  length := s.Len() // length is 0
 	Clear()
+}
+
+// ReadOnlySet is the subset of Set that does not mutate it.
+// It is implemented by every set, including the read-only views returned by Freeze,
+// and it is accepted by all operations comparing sets.
+type ReadOnlySet[Key any] interface {
+	// Len returns the number of elements in the set.
+	// Example:
+	//  s := kset.HashMapKey(1, 2)
+	//  length := s.Len() // length is 2
+	Len() int
 
 	// Contains checks if all specified elements are present in the set.
 	// It returns true if all elements v are in the set, false otherwise.
@@ -52,7 +64,7 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(1, 2)
 	//  isProper := s1.IsProperSubset(s2) // isProper is true
 	//  isProper = s1.IsProperSubset(s3) // isProper is false
-	IsProperSubset(other Set[Key]) bool
+	IsProperSubset(other ReadOnlySet[Key]) bool
 
 	// IsProperSuperset checks if the set is a proper superset of another set.
 	// A proper superset is a superset that is not equal to the other set.
@@ -62,7 +74,7 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(1, 2, 3)
 	//  isProper := s1.IsProperSuperset(s2) // isProper is true
 	//  isProper = s1.IsProperSuperset(s3) // isProper is false
-	IsProperSuperset(other Set[Key]) bool
+	IsProperSuperset(other ReadOnlySet[Key]) bool
 
 	// IsSubset checks if the set is a subset of another set (i.e., all elements of the current set are also in the other set).
 	// Example:
@@ -71,7 +83,7 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(1, 3)
 	//  isSub := s1.IsSubset(s2) // isSub is true
 	//  isSub = s1.IsSubset(s3) // isSub is false
-	IsSubset(other Set[Key]) bool
+	IsSubset(other ReadOnlySet[Key]) bool
 
 	// IsSuperset checks if the set is a superset of another set (i.e., all elements of the other set are also in the current set).
 	// Example:
@@ -80,7 +92,7 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(1, 4)
 	//  isSuper := s1.IsSuperset(s2) // isSuper is true
 	//  isSuper = s1.IsSuperset(s3) // isSuper is false
-	IsSuperset(other Set[Key]) bool
+	IsSuperset(other ReadOnlySet[Key]) bool
 
 	// Intersects checks if the set has at least one element in common with another set.
 	// Example:
@@ -89,7 +101,7 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(4, 5)
 	//  intersects := s1.Intersects(s2) // intersects is true
 	//  intersects = s1.Intersects(s3) // intersects is false
-	Intersects(other Set[Key]) bool
+	Intersects(other ReadOnlySet[Key]) bool
 
 	// Equal checks if the set is equal to another set (i.e., contains the same elements).
 	// Example:
@@ -98,11 +110,18 @@ type Set[Key any] interface {
 	//  s3 := kset.HashMapKey(1, 3)
 	//  isEqual := s1.Equal(s2) // isEqual is true
 	//  isEqual = s1.Equal(s3) // isEqual is false
-	Equal(other Set[Key]) bool
+	Equal(other ReadOnlySet[Key]) bool
 
 	// Keys iterates through all keys stored in the set.
+	// Thread-safe sets iterate through a snapshot taken when the loop starts, without holding their locks,
+	// so the loop body can modify the set, such as when filtering it in place.
 	// Example:
 	//	set := kset.HashMapKey(1, 2, 3)
 	//	set.Keys() // returns iter[1, 2, 3]
+	//	for key := range set.Keys() {
+	//		if key%2 == 0 {
+	//			set.RemoveKeys(key) // set is {1, 3}
+	//		}
+	//	}
 	Keys() iter.Seq[Key]
 }
@@ -0,0 +1,302 @@
+package kset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+)
+
+// ErrInvalidArray is returned when scanning a column that is not a valid array literal.
+var ErrInvalidArray = errors.New("kset: invalid array literal")
+
+// arraySpace holds the whitespace characters ignored around the elements of array literals.
+const arraySpace = " \t\n\r\v\f"
+
+// SQLKey is the constraint of the keys that can be stored in SQL array and JSON columns.
+type SQLKey interface {
+	~string | constraints.Integer | constraints.Float
+}
+
+// SQLColumn is a set adapted to be scanned from and stored into a SQL column.
+type SQLColumn interface {
+	sql.Scanner
+	driver.Valuer
+}
+
+// PostgresArray adapts the set to a Postgres array column, such as text[] or bigint[].
+// It is stored as an array literal with its keys in ascending order, such as {a,b,"c d"}.
+// Scanning replaces the keys of the set, keeping its storage, and follows the decoding rules of Set.
+// Array literals holding NULL elements are invalid.
+// Example:
+//
+//	tags := kset.HashMapKey[string]()
+//	err := db.QueryRow("SELECT tags FROM posts WHERE id = $1", id).Scan(kset.PostgresArray(tags))
+//	_, err = db.Exec("UPDATE posts SET tags = $1 WHERE id = $2", kset.PostgresArray(tags), id)
+func PostgresArray[Key SQLKey](set KeySet[Key]) SQLColumn {
+	return postgresArray[Key]{set}
+}
+
+// JSONArray adapts the set to a JSON column, such as the JSON text columns of SQLite.
+// It is stored as a JSON array with its keys in ascending order.
+// Scanning replaces the keys of the set, keeping its storage, and follows the decoding rules of Set.
+// Example:
+//
+//	roles := kset.HashMapKey[string]()
+//	err := db.QueryRow("SELECT roles FROM users WHERE id = ?", id).Scan(kset.JSONArray(roles))
+//	_, err = db.Exec("UPDATE users SET roles = ? WHERE id = ?", kset.JSONArray(roles), id)
+func JSONArray[Key SQLKey](set KeySet[Key]) SQLColumn {
+	return jsonArray[Key]{set}
+}
+
+type postgresArray[Key SQLKey] struct {
+	set KeySet[Key]
+}
+
+func (p postgresArray[Key]) Value() (driver.Value, error) {
+	keys := p.set.Slice()
+	slices.Sort(keys)
+
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		writeArrayElement(&builder, formatKey(key))
+	}
+	builder.WriteByte('}')
+	return builder.String(), nil
+}
+
+func (p postgresArray[Key]) Scan(src any) error {
+	text, ok, err := scanText(src)
+	if err != nil {
+		return err
+	}
+	var keys []Key
+	if ok {
+		elements, err := parseArray(text)
+		if err != nil {
+			return err
+		}
+
+		keys = make([]Key, len(elements))
+		for i, element := range elements {
+			if keys[i], err = parseKey[Key](element); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidArray, err)
+			}
+		}
+	}
+
+	return replaceKeys(p.set, func(add func([]Key) error) error {
+		return add(keys)
+	})
+}
+
+type jsonArray[Key SQLKey] struct {
+	set KeySet[Key]
+}
+
+func (j jsonArray[Key]) Value() (driver.Value, error) {
+	data, err := SortedJSON[Key](j.set).MarshalJSON()
+	return string(data), err
+}
+
+func (j jsonArray[Key]) Scan(src any) error {
+	text, ok, err := scanText(src)
+	if err != nil {
+		return err
+	}
+	var keys []Key
+	if ok {
+		if err := json.Unmarshal([]byte(text), &keys); err != nil {
+			return err
+		}
+	}
+
+	return replaceKeys(j.set, func(add func([]Key) error) error {
+		return add(keys)
+	})
+}
+
+// scanText returns the text of a column, or false if it is NULL.
+func scanText(src any) (string, bool, error) {
+	switch src := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return src, true, nil
+	case []byte:
+		return string(src), true, nil
+	default:
+		return "", false, fmt.Errorf("kset: cannot scan %T into a set", src)
+	}
+}
+
+// parseArray returns the elements of a one-dimensional Postgres array literal.
+// Elements can be quoted, and backslashes escape the following character.
+func parseArray(text string) ([]string, error) {
+	// Arrays with non-default bounds are prefixed by their dimensions, such as [0:1]={a,b}.
+	if strings.HasPrefix(text, "[") {
+		i := strings.IndexByte(text, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidArray, text)
+		}
+		text = text[i+1:]
+	}
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidArray, text)
+	}
+
+	body := text[1 : len(text)-1]
+	if strings.TrimSpace(body) == "" {
+		return nil, nil
+	}
+
+	var elements []string
+	var element strings.Builder
+	for i := 0; ; i++ {
+		element.Reset()
+		quoted := false
+
+		for i < len(body) && isArraySpace(body[i]) {
+			i++
+		}
+		if i < len(body) && body[i] == '"' {
+			quoted = true
+			for i++; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' {
+					i++
+				}
+				if i < len(body) {
+					element.WriteByte(body[i])
+				}
+			}
+			if i == len(body) {
+				return nil, fmt.Errorf("%w: unterminated quote in %q", ErrInvalidArray, text)
+			}
+			i++
+			for i < len(body) && isArraySpace(body[i]) {
+				i++
+			}
+		} else {
+			for ; i < len(body) && body[i] != ','; i++ {
+				switch body[i] {
+				case '{', '}', '"':
+					return nil, fmt.Errorf("%w: unexpected %q in %q", ErrInvalidArray, body[i], text)
+				case '\\':
+					i++
+				}
+				if i < len(body) {
+					element.WriteByte(body[i])
+				}
+			}
+		}
+
+		value := element.String()
+		if !quoted {
+			value = strings.TrimRight(value, arraySpace)
+			if value == "" {
+				return nil, fmt.Errorf("%w: empty element in %q", ErrInvalidArray, text)
+			}
+			if strings.EqualFold(value, "NULL") {
+				return nil, fmt.Errorf("%w: sets cannot hold NULL elements", ErrInvalidArray)
+			}
+		}
+		elements = append(elements, value)
+
+		if i == len(body) {
+			return elements, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("%w: unexpected %q in %q", ErrInvalidArray, body[i], text)
+		}
+	}
+}
+
+func isArraySpace(c byte) bool {
+	return strings.IndexByte(arraySpace, c) >= 0
+}
+
+// writeArrayElement writes an element of an array literal, quoting it if it would not be read back as is.
+func writeArrayElement(builder *strings.Builder, element string) {
+	if element != "" && !strings.EqualFold(element, "NULL") && !strings.ContainsAny(element, `{},"\`+arraySpace) {
+		builder.WriteString(element)
+		return
+	}
+
+	builder.WriteByte('"')
+	for i := range len(element) {
+		if element[i] == '"' || element[i] == '\\' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(element[i])
+	}
+	builder.WriteByte('"')
+}
+
+func formatKey[Key SQLKey](key Key) string {
+	value := reflect.ValueOf(key)
+	switch kind := value.Kind(); {
+	case kind == reflect.String:
+		return value.String()
+	case isSigned(kind):
+		return strconv.FormatInt(value.Int(), 10)
+	case isUnsigned(kind):
+		return strconv.FormatUint(value.Uint(), 10)
+	default:
+		return formatFloat(value.Float(), value.Type().Bits())
+	}
+}
+
+// formatFloat formats the float as Postgres does, spelling infinities as Infinity and -Infinity.
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+}
+
+func parseKey[Key SQLKey](text string) (Key, error) {
+	var key Key
+	value := reflect.ValueOf(&key).Elem()
+	switch kind := value.Kind(); {
+	case kind == reflect.String:
+		value.SetString(text)
+	case isSigned(kind):
+		i, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return key, err
+		}
+		value.SetInt(i)
+	case isUnsigned(kind):
+		u, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return key, err
+		}
+		value.SetUint(u)
+	default:
+		// ParseFloat also accepts the Infinity, -Infinity and NaN spellings of Postgres.
+		f, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return key, err
+		}
+		value.SetFloat(f)
+	}
+	return key, nil
+}
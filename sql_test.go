@@ -0,0 +1,225 @@
+package kset_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver stores the first argument of every Exec as its only column, and returns it from every Query.
+// Strings are returned as []byte, like most drivers do for text and array columns.
+type fakeDriver struct {
+	mutex  sync.Mutex
+	column driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ driver *fakeDriver }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ driver *fakeDriver }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.mutex.Lock()
+	defer s.driver.mutex.Unlock()
+	s.driver.column = args[0]
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.driver.mutex.Lock()
+	defer s.driver.mutex.Unlock()
+	if text, ok := s.driver.column.(string); ok {
+		return &fakeRows{value: []byte(text)}, nil
+	}
+	return &fakeRows{value: s.driver.column}, nil
+}
+
+type fakeRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"column"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = r.value, true
+	return nil
+}
+
+var fakeDB = sync.OnceValue(func() *fakeDriver {
+	d := &fakeDriver{}
+	sql.Register("kset-fake", d)
+	return d
+})
+
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDriver) {
+	d := fakeDB()
+	db, err := sql.Open("kset-fake", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func Test_PostgresArray(t *testing.T) {
+	db, d := openFakeDB(t)
+
+	t.Run("round trip", func(t *testing.T) {
+		tags := kset.HashMapKey("b", "a", "c d", `quo"te`, `back\slash`, "", "null", "{x}")
+
+		_, err := db.Exec("UPDATE", kset.PostgresArray(tags))
+		require.NoError(t, err)
+		assert.Equal(t, `{"",a,b,"back\\slash","c d","null","quo\"te","{x}"}`, d.column)
+
+		decoded := kset.TreeMapKey("stale")
+		require.NoError(t, db.QueryRow("SELECT").Scan(kset.PostgresArray(decoded)))
+		assert.True(t, tags.Equal(decoded))
+	})
+
+	t.Run("integers", func(t *testing.T) {
+		ids := kset.HashMapKey[int64](3, -1, 2)
+
+		_, err := db.Exec("UPDATE", kset.PostgresArray(ids))
+		require.NoError(t, err)
+		assert.Equal(t, `{-1,2,3}`, d.column)
+
+		decoded := kset.HashMapKey[int64]()
+		require.NoError(t, db.QueryRow("SELECT").Scan(kset.PostgresArray(decoded)))
+		assert.True(t, ids.Equal(decoded))
+	})
+
+	t.Run("null column", func(t *testing.T) {
+		_, err := db.Exec("UPDATE", nil)
+		require.NoError(t, err)
+
+		set := kset.HashMapKey("a")
+		require.NoError(t, db.QueryRow("SELECT").Scan(kset.PostgresArray(set)))
+		assert.True(t, set.IsEmpty())
+	})
+
+	t.Run("unsupported column", func(t *testing.T) {
+		require.Error(t, kset.PostgresArray(kset.HashMapKey[int]()).Scan(int64(1)))
+	})
+
+	t.Run("literals", func(t *testing.T) {
+		tests := []struct {
+			literal  string
+			expected []string
+		}{
+			{literal: `{}`, expected: []string{}},
+			{literal: `{a,b,"c d"}`, expected: []string{"a", "b", "c d"}},
+			{literal: "{ a , \"b\" ,\tc\n}", expected: []string{"a", "b", "c"}},
+			{literal: `{"esc\"aped","back\\slash",un\,quoted}`, expected: []string{`back\slash`, `esc"aped`, "un,quoted"}},
+			{literal: `{"",NULLABLE,"NULL"}`, expected: []string{"", "NULL", "NULLABLE"}},
+			{literal: `[0:1]={x,y}`, expected: []string{"x", "y"}},
+		}
+
+		for _, tc := range tests {
+			set := kset.TreeMapKey[string]()
+			require.NoError(t, kset.PostgresArray(set).Scan(tc.literal), tc.literal)
+			assert.Equal(t, tc.expected, set.Slice(), tc.literal)
+		}
+	})
+
+	t.Run("invalid literals", func(t *testing.T) {
+		for _, literal := range []string{`a,b`, `{a,NULL}`, `{{1,2},{3,4}}`, `{"a}`, `{a,,b}`, `{a,}`, `{"a"b}`, `[0:1]`} {
+			set := kset.HashMapKey("kept")
+			require.ErrorIs(t, kset.PostgresArray(set).Scan(literal), kset.ErrInvalidArray, literal)
+			assert.Equal(t, []string{"kept"}, set.Slice(), literal)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		set := kset.HashMapKey[int8]()
+		require.ErrorIs(t, kset.PostgresArray(set).Scan(`{1,300}`), kset.ErrInvalidArray)
+		require.ErrorIs(t, kset.PostgresArray(kset.HashMapKey[uint]()).Scan(`{-1}`), kset.ErrInvalidArray)
+		assert.True(t, set.IsEmpty())
+	})
+
+	t.Run("keys rejected by the storage", func(t *testing.T) {
+		set := kset.BitSetKey(1, 2)
+		require.ErrorIs(t, kset.PostgresArray(set).Scan(`{3,-1}`), kset.ErrInvalidKey)
+		assert.Equal(t, []int{1, 2}, set.Slice())
+	})
+
+	t.Run("floats", func(t *testing.T) {
+		set := kset.TreeMapKey(0.5, -2.25, 1e21)
+		value, err := kset.PostgresArray(set).Value()
+		require.NoError(t, err)
+		assert.Equal(t, `{-2.25,0.5,1e+21}`, value)
+
+		decoded := kset.HashMapKey[float64]()
+		require.NoError(t, kset.PostgresArray(decoded).Scan(value))
+		assert.True(t, set.Equal(decoded))
+	})
+	t.Run("special floats", func(t *testing.T) {
+		set := kset.TreeMapKey(math.Inf(1), 1.5, math.Inf(-1), math.NaN())
+		value, err := kset.PostgresArray(set).Value()
+		require.NoError(t, err)
+		assert.Equal(t, `{NaN,-Infinity,1.5,Infinity}`, value)
+
+		decoded := kset.TreeMapKey[float32]()
+		require.NoError(t, kset.PostgresArray(decoded).Scan(value))
+		keys := decoded.Slice()
+		require.Len(t, keys, 4)
+		assert.True(t, math.IsNaN(float64(keys[0])))
+		assert.Equal(t, []float32{float32(math.Inf(-1)), 1.5, float32(math.Inf(1))}, keys[1:])
+	})
+}
+
+func Test_JSONArray(t *testing.T) {
+	db, d := openFakeDB(t)
+
+	t.Run("round trip", func(t *testing.T) {
+		roles := kset.HashMapKey("viewer", "admin", `"quoted"`)
+
+		_, err := db.Exec("UPDATE", kset.JSONArray(roles))
+		require.NoError(t, err)
+		assert.Equal(t, `["\"quoted\"","admin","viewer"]`, d.column)
+
+		decoded := kset.TreeMapKey("stale")
+		require.NoError(t, db.QueryRow("SELECT").Scan(kset.JSONArray(decoded)))
+		assert.True(t, roles.Equal(decoded))
+	})
+
+	t.Run("null column", func(t *testing.T) {
+		_, err := db.Exec("UPDATE", nil)
+		require.NoError(t, err)
+
+		set := kset.HashMapKey(1)
+		require.NoError(t, db.QueryRow("SELECT").Scan(kset.JSONArray(set)))
+		assert.True(t, set.IsEmpty())
+	})
+
+	t.Run("invalid column", func(t *testing.T) {
+		set := kset.HashMapKey(1)
+		require.Error(t, kset.JSONArray(set).Scan(`["a"]`))
+		require.Error(t, kset.JSONArray(set).Scan(`{"a": 1}`))
+		assert.Equal(t, []int{1}, set.Slice())
+	})
+
+	t.Run("keys rejected by the storage", func(t *testing.T) {
+		set := kset.BitSetKey(1, 2)
+		require.ErrorIs(t, kset.JSONArray(set).Scan(`[3, -1]`), kset.ErrInvalidKey)
+		assert.Equal(t, []int{1, 2}, set.Slice())
+	})
+}
@@ -0,0 +1,369 @@
+// Package storagetest provides a conformance test suite for kset.Storage implementations.
+// The optional kset.BatchStorage operations are only tested if the storage implements them.
+//
+// Example:
+//
+//	func TestMyStorage(t *testing.T) {
+//		storagetest.Run(t, newMyStorage, func(i int) (int, string) {
+//			return i, strconv.Itoa(i)
+//		})
+//	}
+package storagetest
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entryCount is the number of distinct entries used by each test case.
+const entryCount = 64
+
+// Run exercises the kset.Storage contract against storages created by newStorage.
+// entry must return a distinct key for every index, together with any value for it.
+func Run[Key comparable, Value any](t *testing.T, newStorage func() kset.Storage[Key, Value], entry func(i int) (Key, Value)) {
+	t.Helper()
+
+	s := suite[Key, Value]{
+		newStorage: newStorage,
+		entry:      entry,
+	}
+
+	t.Run("Len", s.testLen)
+	t.Run("Clear", s.testClear)
+	t.Run("Delete", s.testDelete)
+	t.Run("Contains", s.testContains)
+	t.Run("Get", s.testGet)
+	t.Run("Upsert", s.testUpsert)
+	t.Run("UpsertMany", s.testUpsertMany)
+	t.Run("DeleteMany", s.testDeleteMany)
+	t.Run("Iter", s.testIter)
+	t.Run("IterBreak", s.testIterBreak)
+	t.Run("Clone", s.testClone)
+}
+
+// RunConcurrent exercises thread-safe kset.Storage implementations with concurrent readers and writers.
+// It should be executed with the -race flag, on top of Run.
+func RunConcurrent[Key comparable, Value any](t *testing.T, newStorage func() kset.Storage[Key, Value], entry func(i int) (Key, Value)) {
+	t.Helper()
+
+	s := suite[Key, Value]{
+		newStorage: newStorage,
+		entry:      entry,
+	}
+
+	t.Run("Concurrent", s.testConcurrent)
+	t.Run("ConcurrentBatches", s.testConcurrentBatches)
+}
+
+type suite[Key comparable, Value any] struct {
+	newStorage func() kset.Storage[Key, Value]
+	entry      func(i int) (Key, Value)
+}
+
+// batch returns the batch operations of the storage, skipping the test if it has none.
+func batchOf[Key, Value any](t *testing.T, storage kset.Storage[Key, Value]) kset.BatchStorage[Key, Value] {
+	batch, ok := storage.(kset.BatchStorage[Key, Value])
+	if !ok {
+		t.Skip("storage has no batch operations")
+	}
+	return batch
+}
+
+// populated returns a storage with the first n entries, and the expected content of it.
+func (s suite[Key, Value]) populated(t *testing.T, n int) (kset.Storage[Key, Value], map[Key]Value) {
+	storage := s.newStorage()
+	require.Zero(t, storage.Len(), "new storage must be empty")
+
+	expected := make(map[Key]Value, n)
+	for i := range n {
+		key, value := s.entry(i)
+		storage.Upsert(key, value)
+		expected[key] = value
+	}
+	require.Len(t, expected, n, "entry must return distinct keys")
+
+	return storage, expected
+}
+
+func (s suite[Key, Value]) assertContent(t *testing.T, storage kset.Storage[Key, Value], expected map[Key]Value) {
+	t.Helper()
+
+	assert.Equal(t, len(expected), storage.Len())
+
+	got := make(map[Key]Value, storage.Len())
+	for key, value := range storage.Iter() {
+		_, duplicate := got[key]
+		assert.False(t, duplicate, "key %v iterated more than once", key)
+		got[key] = value
+	}
+	assert.Equal(t, expected, got)
+}
+
+func (s suite[Key, Value]) testLen(t *testing.T) {
+	storage, _ := s.populated(t, entryCount)
+	assert.Equal(t, entryCount, storage.Len())
+
+	key, value := s.entry(0)
+	storage.Upsert(key, value)
+	assert.Equal(t, entryCount, storage.Len(), "upserting an existing key must not change the length")
+}
+
+func (s suite[Key, Value]) testClear(t *testing.T) {
+	storage, _ := s.populated(t, entryCount)
+	storage.Clear()
+	s.assertContent(t, storage, map[Key]Value{})
+
+	storage.Clear()
+	assert.Zero(t, storage.Len(), "clearing an empty storage must be a no-op")
+
+	key, value := s.entry(0)
+	storage.Upsert(key, value)
+	s.assertContent(t, storage, map[Key]Value{key: value})
+}
+
+func (s suite[Key, Value]) testDelete(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	storage.Delete()
+	s.assertContent(t, storage, expected)
+
+	first, _ := s.entry(0)
+	second, _ := s.entry(1)
+	missing, _ := s.entry(entryCount)
+
+	storage.Delete(first, second, missing, first)
+	delete(expected, first)
+	delete(expected, second)
+	s.assertContent(t, storage, expected)
+	assert.False(t, storage.Contains(first))
+	assert.False(t, storage.Contains(second))
+}
+
+func (s suite[Key, Value]) testContains(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	for key := range expected {
+		assert.True(t, storage.Contains(key), "key %v", key)
+	}
+
+	missing, _ := s.entry(entryCount)
+	assert.False(t, storage.Contains(missing))
+}
+
+func (s suite[Key, Value]) testGet(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	for key, want := range expected {
+		value, ok := storage.Get(key)
+		assert.True(t, ok, "key %v", key)
+		assert.Equal(t, want, value, "key %v", key)
+	}
+
+	missing, _ := s.entry(entryCount)
+	value, ok := storage.Get(missing)
+	assert.False(t, ok)
+	assert.Zero(t, value)
+}
+
+func (s suite[Key, Value]) testUpsert(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	key, _ := s.entry(0)
+	_, replacement := s.entry(1)
+	storage.Upsert(key, replacement)
+	expected[key] = replacement
+
+	value, ok := storage.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, replacement, value)
+	s.assertContent(t, storage, expected)
+}
+
+func (s suite[Key, Value]) testUpsertMany(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+	batch := batchOf(t, storage)
+
+	assert.Empty(t, batch.UpsertMany(nil, nil))
+	s.assertContent(t, storage, expected)
+
+	existing, _ := s.entry(0)
+	added, _ := s.entry(entryCount)
+	other, _ := s.entry(entryCount + 1)
+	_, first := s.entry(1)
+	_, last := s.entry(2)
+
+	inserted := batch.UpsertMany([]Key{existing, added, added, other}, []Value{first, first, last, last})
+	assert.Equal(t, []bool{false, true, false, true}, inserted, "repeated keys must only be reported as inserted once")
+	expected[existing] = first
+	expected[added] = last
+	expected[other] = last
+	s.assertContent(t, storage, expected)
+
+	zero, _ := s.entry(entryCount + 2)
+	inserted = batch.UpsertMany([]Key{zero, existing}, nil)
+	assert.Equal(t, []bool{true, false}, inserted)
+	var value Value
+	expected[zero] = value
+	expected[existing] = value
+	s.assertContent(t, storage, expected)
+}
+
+func (s suite[Key, Value]) testDeleteMany(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+	batch := batchOf(t, storage)
+
+	assert.Empty(t, batch.DeleteMany(nil))
+	s.assertContent(t, storage, expected)
+
+	first, _ := s.entry(0)
+	second, _ := s.entry(1)
+	missing, _ := s.entry(entryCount)
+
+	deleted := batch.DeleteMany([]Key{second, missing, first, second})
+	assert.Equal(t, []bool{true, false, true, false}, deleted, "repeated keys must only be reported as deleted once")
+	delete(expected, first)
+	delete(expected, second)
+	s.assertContent(t, storage, expected)
+}
+
+func (s suite[Key, Value]) testIter(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+	s.assertContent(t, storage, expected)
+
+	empty := s.newStorage()
+	for key := range empty.Iter() {
+		t.Fatalf("empty storage yielded key %v", key)
+	}
+}
+
+func (s suite[Key, Value]) testIterBreak(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	count := 0
+	for range storage.Iter() {
+		count++
+		if count == entryCount/2 {
+			break
+		}
+	}
+	assert.Equal(t, entryCount/2, count)
+
+	// The storage must remain usable after an early break.
+	key, value := s.entry(entryCount)
+	storage.Upsert(key, value)
+	expected[key] = value
+	s.assertContent(t, storage, expected)
+}
+
+func (s suite[Key, Value]) testClone(t *testing.T) {
+	storage, expected := s.populated(t, entryCount)
+
+	clone := storage.Clone()
+	s.assertContent(t, clone, expected)
+
+	first, _ := s.entry(0)
+	clone.Delete(first)
+	key, value := s.entry(entryCount)
+	clone.Upsert(key, value)
+	s.assertContent(t, storage, expected)
+
+	second, _ := s.entry(1)
+	storage.Delete(second)
+	assert.True(t, clone.Contains(second), "mutating the original must not affect the clone")
+
+	clone.Clear()
+	assert.Equal(t, entryCount-1, storage.Len())
+}
+
+func (s suite[Key, Value]) testConcurrent(t *testing.T) {
+	const workers = 8
+
+	storage := s.newStorage()
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range entryCount {
+				key, value := s.entry(worker*entryCount + i)
+				storage.Upsert(key, value)
+				storage.Contains(key)
+				storage.Get(key)
+				storage.Len()
+				if i%4 == 0 {
+					storage.Delete(key)
+				}
+				if i%16 == 0 {
+					for range storage.Iter() {
+					}
+					storage.Clone()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, workers*(entryCount-entryCount/4), storage.Len())
+	for worker := range workers {
+		for i := range entryCount {
+			key, _ := s.entry(worker*entryCount + i)
+			assert.Equal(t, i%4 != 0, storage.Contains(key), "key %v", key)
+		}
+	}
+}
+
+// testConcurrentBatches upserts and deletes overlapping batches from several workers,
+// verifying the per-key outcomes account for every change of the storage.
+func (s suite[Key, Value]) testConcurrentBatches(t *testing.T) {
+	const workers = 8
+
+	storage := s.newStorage()
+	batch := batchOf(t, storage)
+
+	keys := make([]Key, entryCount)
+	values := make([]Value, entryCount)
+	for i := range entryCount {
+		keys[i], values[i] = s.entry(i)
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	inserted, deleted := 0, 0
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			localInserted, localDeleted := 0, 0
+			for i := range entryCount {
+				offset := (worker + i) % entryCount
+				batchKeys := append(slices.Clone(keys[offset:]), keys[:offset]...)
+				for _, ok := range batch.UpsertMany(batchKeys, append(slices.Clone(values[offset:]), values[:offset]...)) {
+					if ok {
+						localInserted++
+					}
+				}
+				if i%2 != 0 {
+					continue
+				}
+				for _, ok := range batch.DeleteMany(batchKeys[:entryCount/2]) {
+					if ok {
+						localDeleted++
+					}
+				}
+			}
+			mutex.Lock()
+			inserted += localInserted
+			deleted += localDeleted
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, inserted-deleted, storage.Len())
+}
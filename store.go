@@ -1,20 +1,121 @@
 package kset
 
 import (
+	"errors"
 	"iter"
 )
 
+// ErrInvalidKey is returned when decoding keys that the storage of the set cannot hold, such as negative keys for BitSetKey.
+var ErrInvalidKey = errors.New("kset: key not supported by the storage")
+
 type (
+	// Storage is the underlying data structure used by the sets.
+	// Implementations can be provided to NewKeySet and NewKeyValueSet to use a custom backend,
+	// while still getting the full KeySet and KeyValueSet operations.
+	// Key sets use struct{} as Value.
+	// Implementations can also implement BatchStorage to apply batches at once.
+	// Otherwise batches are applied one by one, so counts and events are only exact for storages not shared across goroutines.
 	Storage[Key, Value any] interface {
+		// Len returns the number of entries in the storage.
 		Len() int
+		// Clear removes all entries from the storage.
 		Clear()
+		// Delete removes the given keys from the storage, ignoring missing ones.
 		Delete(...Key)
+		// Contains reports whether the key is present in the storage.
 		Contains(Key) bool
+		// Get returns the value stored for the key, and whether it was found.
 		Get(Key) (Value, bool)
+		// Upsert inserts or replaces the value stored for the key.
 		Upsert(Key, Value)
+		// Iter iterates through all entries of the storage.
+		// Thread-safe storages can hold their read lock while yielding, so the loop body must not modify the storage.
 		Iter() iter.Seq2[Key, Value]
+		// Clone returns an independent copy of the storage.
 		Clone() Storage[Key, Value]
 	}
 
+	// BatchStorage is a storage able to apply many upserts or deletes at once.
+	// Storages provided to NewKeySet and NewKeyValueSet can implement it, otherwise batches are applied one by one.
+	// Thread-safe storages should implement it, as the one by one fallback is not atomic:
+	// the counts returned by Append and the events delivered to observers can then miss concurrent changes.
+	BatchStorage[Key, Value any] interface {
+		// UpsertMany inserts or replaces the values stored for the keys, as if upserted one by one in order.
+		// values can be nil, in which case all values are zero.
+		// It returns, for each key, whether it was inserted rather than replaced.
+		// Thread-safe storages apply the whole batch under a single lock acquisition, so it is never observed half-applied.
+		UpsertMany(keys []Key, values []Value) []bool
+		// DeleteMany removes the given keys, as if deleted one by one in order.
+		// It returns, for each key, whether it was found and removed.
+		// Thread-safe storages apply the whole batch under a single lock acquisition, so it is never observed half-applied.
+		DeleteMany(keys []Key) []bool
+	}
+
+	// orderedStorage is a storage that keeps its keys sorted, allowing ordered navigation.
+	// Range iterates through keys in [from, to).
+	// Rank returns the number of keys less than the given key, and At returns the i-th smallest entry.
+	orderedStorage[Key, Value any] interface {
+		Storage[Key, Value]
+		Min() (Key, Value, bool)
+		Max() (Key, Value, bool)
+		Floor(Key) (Key, Value, bool)
+		Ceiling(Key) (Key, Value, bool)
+		Lower(Key) (Key, Value, bool)
+		Higher(Key) (Key, Value, bool)
+		Rank(Key) int
+		At(int) (Key, Value, bool)
+		Range(from, to Key) iter.Seq2[Key, Value]
+		Backward() iter.Seq2[Key, Value]
+	}
+
+	// sortedStorage is an ordered storage that can be bulk loaded from ascending entries.
+	// It allows set operations between sorted sets to be computed through a linear merge.
+	sortedStorage[Key, Value any] interface {
+		orderedStorage[Key, Value]
+		// compare is the ordering of the storage keys.
+		compare(a, b Key) int
+		// loadSorted returns a new storage of the same kind, containing the given strictly ascending entries.
+		// values can be nil, in which case all values are zero.
+		loadSorted(keys []Key, values []Value) Storage[Key, Value]
+	}
+
+	// lockingStorage is a thread-safe storage able to run fn while holding its write lock,
+	// passing a view of itself that does not lock, or a private version of itself published when fn returns,
+	// so many operations can be applied atomically.
+	lockingStorage[Key, Value any] interface {
+		locked(fn func(view Storage[Key, Value]))
+	}
+
+	// computingStorage is a thread-safe storage able to replace the entry of a key atomically,
+	// holding only the locks needed for that key while fn runs.
+	computingStorage[Key, Value any] interface {
+		compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool)
+	}
+
+	// snapshotStorage is a thread-safe storage holding its read lock while iterating,
+	// able to iterate through a copy of its entries instead, without holding its lock while yielding.
+	// Keys and KeyValues iterate through it, so the loop body can modify the set.
+	snapshotStorage[Key, Value any] interface {
+		iterSnapshot() iter.Seq2[Key, Value]
+	}
+
+	// validatingStorage is a storage unable to hold some keys of its type, panicking when they are upserted.
+	// validate returns an error wrapping ErrInvalidKey for the first key it cannot hold.
+	validatingStorage[Key any] interface {
+		validate(keys []Key) error
+	}
+
+	// combinableStorage is a storage able to compute set operations natively against another storage of the same type.
+	combinableStorage[Store any] interface {
+		combine(other Store, op mergeOp) Store
+	}
+
 	empty = struct{}
 )
+
+// storeView is the view passed by locked when it is a store of the same type, not shared with other goroutines.
+// It hides the locked method of the store, so algorithms given the view do not lock it again.
+type storeView[Key, Value any] struct {
+	Storage[Key, Value]
+	BatchStorage[Key, Value]
+}
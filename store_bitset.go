@@ -0,0 +1,270 @@
+package kset
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+const wordSize = 64
+
+// bitSetStore is a word-packed bitmap, where each key is a bit position.
+type bitSetStore[Key constraints.Integer] struct {
+	mutex sync.RWMutex
+	words []uint64
+}
+
+// BitSetKey is a thread-safe dense bitset key set implementation, for small non-negative integer keys.
+// Each key takes a single bit, and memory is proportional to the greatest key stored.
+// Set operations between two bitsets are computed word by word.
+// Appending a negative key panics.
+//
+//	Operation		Average		WorstCase
+//	Search			O(1)		O(1)
+//	Insert			O(1)		O(max/64)
+//	Delete			O(1)		O(max/64)
+//	Len			O(max/64)	O(max/64)
+//	Union/Intersect		O(max/64)	O(max/64)
+//
+// Space complexity
+//
+//	Space			O(max/64)	O(max/64)
+func BitSetKey[Key constraints.Integer](keys ...Key) KeySet[Key] {
+	store := &bitSetStore[Key]{}
+
+	for _, key := range keys {
+		store.set(key)
+	}
+
+	return &keySet[Key, *bitSetStore[Key]]{
+		store: store,
+	}
+}
+
+// position returns the word index and bit mask of the key, or false if the key is negative.
+func position[Key constraints.Integer](key Key) (int, uint64, bool) {
+	if key < 0 {
+		return 0, 0, false
+	}
+	return int(uint64(key) / wordSize), 1 << (uint64(key) % wordSize), true
+}
+
+// set turns the bit of the key on, growing the bitmap if needed. It returns whether the bit was off.
+func (b *bitSetStore[Key]) set(key Key) bool {
+	i, mask, ok := position(key)
+	if !ok {
+		panic("kset: bitset keys must not be negative")
+	}
+	if n := len(b.words); i >= n {
+		b.words = slices.Grow(b.words, i+1-n)[:i+1]
+		clear(b.words[n:])
+	}
+	if b.words[i]&mask != 0 {
+		return false
+	}
+	b.words[i] |= mask
+	return true
+}
+
+// unset turns the bit of the key off, returning whether it was on.
+func (b *bitSetStore[Key]) unset(key Key) bool {
+	i, mask, ok := position(key)
+	if !ok || i >= len(b.words) || b.words[i]&mask == 0 {
+		return false
+	}
+	b.words[i] &^= mask
+	return true
+}
+
+// trim removes trailing empty words, so memory follows the greatest key stored.
+func (b *bitSetStore[Key]) trim() {
+	end := len(b.words)
+	for end > 0 && b.words[end-1] == 0 {
+		end--
+	}
+	b.words = b.words[:end]
+}
+
+func (b *bitSetStore[Key]) Clear() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.words = nil
+}
+
+func (b *bitSetStore[Key]) Clone() Storage[Key, empty] {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return &bitSetStore[Key]{
+		words: slices.Clone(b.words),
+	}
+}
+
+func (b *bitSetStore[Key]) Contains(key Key) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	i, mask, ok := position(key)
+	return ok && i < len(b.words) && b.words[i]&mask != 0
+}
+
+func (b *bitSetStore[Key]) Delete(keys ...Key) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, key := range keys {
+		b.unset(key)
+	}
+	b.trim()
+}
+
+func (b *bitSetStore[Key]) DeleteMany(keys []Key) []bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		deleted[i] = b.unset(key)
+	}
+	b.trim()
+	return deleted
+}
+
+func (b *bitSetStore[Key]) Get(key Key) (empty, bool) {
+	return empty{}, b.Contains(key)
+}
+
+// Iter iterates through the keys in ascending order.
+func (b *bitSetStore[Key]) Iter() iter.Seq2[Key, empty] {
+	return func(yield func(Key, empty) bool) {
+		b.mutex.RLock()
+		defer b.mutex.RUnlock()
+		iterWords(b.words, yield)
+	}
+}
+
+// iterSnapshot copies the words, which is cheaper than collecting the keys.
+func (b *bitSetStore[Key]) iterSnapshot() iter.Seq2[Key, empty] {
+	return func(yield func(Key, empty) bool) {
+		b.mutex.RLock()
+		words := slices.Clone(b.words)
+		b.mutex.RUnlock()
+		iterWords(words, yield)
+	}
+}
+
+func iterWords[Key constraints.Integer](words []uint64, yield func(Key, empty) bool) {
+	for i, word := range words {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			if !yield(Key(i*wordSize+bit), empty{}) {
+				return
+			}
+			word &= word - 1
+		}
+	}
+}
+
+// Len counts the keys with a population count over all words.
+func (b *bitSetStore[Key]) Len() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	count := 0
+	for _, word := range b.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+func (b *bitSetStore[Key]) Upsert(key Key, _ empty) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.set(key)
+}
+
+// UpsertMany panics before setting any bit if one of the keys is negative.
+func (b *bitSetStore[Key]) UpsertMany(keys []Key, _ []empty) []bool {
+	if err := b.validate(keys); err != nil {
+		panic("kset: bitset keys must not be negative")
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		inserted[i] = b.set(key)
+	}
+	return inserted
+}
+
+// locked runs fn against a view sharing the words of the store, while holding its write lock.
+// The store adopts the words of the view afterwards, as the view might have reallocated them.
+func (b *bitSetStore[Key]) locked(fn func(view Storage[Key, empty])) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	view := &bitSetStore[Key]{words: b.words}
+	defer func() { b.words = view.words }()
+	fn(storeView[Key, empty]{Storage: view, BatchStorage: view})
+}
+
+func (b *bitSetStore[Key]) validate(keys []Key) error {
+	for _, key := range keys {
+		if key < 0 {
+			return fmt.Errorf("%w: negative bitset key %d", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+// combine computes the operation word by word. The locks of both bitsets are never held at the same time.
+func (b *bitSetStore[Key]) combine(other *bitSetStore[Key], op mergeOp) *bitSetStore[Key] {
+	b.mutex.RLock()
+	words := slices.Clone(b.words)
+	b.mutex.RUnlock()
+
+	other.mutex.RLock()
+	defer other.mutex.RUnlock()
+
+	if (op == mergeUnion || op == mergeSymmetricDifference) && len(other.words) > len(words) {
+		words = append(words, make([]uint64, len(other.words)-len(words))...)
+	}
+	if op == mergeIntersection && len(other.words) < len(words) {
+		words = words[:len(other.words)]
+	}
+
+	for i := range min(len(words), len(other.words)) {
+		switch op {
+		case mergeUnion:
+			words[i] |= other.words[i]
+		case mergeIntersection:
+			words[i] &= other.words[i]
+		case mergeDifference:
+			words[i] &^= other.words[i]
+		case mergeSymmetricDifference:
+			words[i] ^= other.words[i]
+		}
+	}
+
+	result := &bitSetStore[Key]{
+		words: words,
+	}
+	result.trim()
+	return result
+}
+
+var (
+	_ Storage[int, empty]                  = &bitSetStore[int]{}
+	_ BatchStorage[int, empty]             = &bitSetStore[int]{}
+	_ combinableStorage[*bitSetStore[int]] = &bitSetStore[int]{}
+	_ validatingStorage[int]               = &bitSetStore[int]{}
+	_ lockingStorage[int, empty]           = &bitSetStore[int]{}
+)
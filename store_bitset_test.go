@@ -0,0 +1,66 @@
+package kset_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/sonalys/kset/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BitSetKey(t *testing.T) {
+	t.Run("storage", func(t *testing.T) {
+		entry := func(i int) (uint, struct{}) { return uint(i * 7), struct{}{} }
+		storagetest.Run(t, kset.NewBitSetStore[uint], entry)
+		storagetest.RunConcurrent(t, kset.NewBitSetStore[uint], entry)
+	})
+
+	t.Run("equivalence", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(5, 6))
+		set := kset.BitSetKey[int]()
+		expected := kset.HashMapKey[int]()
+
+		for range 5000 {
+			key := rng.IntN(1000)
+			switch rng.IntN(3) {
+			case 0:
+				set.RemoveKeys(key)
+				expected.RemoveKeys(key)
+			default:
+				assert.Equal(t, expected.Append(key), set.Append(key))
+			}
+			assert.Equal(t, expected.Len(), set.Len())
+		}
+
+		assert.True(t, set.Equal(expected))
+	})
+
+	t.Run("algebra", func(t *testing.T) {
+		a := kset.BitSetKey(1, 64, 65, 200, 1000)
+		b := kset.BitSetKey(0, 64, 200)
+		hash := kset.HashMapKey(0, 64, 200)
+
+		for _, other := range []kset.KeySet[int]{b, hash} {
+			assert.ElementsMatch(t, []int{0, 1, 64, 65, 200, 1000}, a.Union(other).Slice())
+			assert.ElementsMatch(t, []int{64, 200}, a.Intersect(other).Slice())
+			assert.ElementsMatch(t, []int{1, 65, 1000}, a.Difference(other).Slice())
+			assert.ElementsMatch(t, []int{0, 1, 65, 1000}, a.SymmetricDifference(other).Slice())
+			assert.ElementsMatch(t, []int{64, 200}, other.Intersect(a).Slice())
+		}
+
+		// Intersections must not leave stale bits behind when growing again.
+		intersection := a.Intersect(b)
+		intersection.Append(500)
+		assert.ElementsMatch(t, []int{64, 200, 500}, intersection.Slice())
+	})
+
+	t.Run("negative keys", func(t *testing.T) {
+		set := kset.BitSetKey(1)
+
+		assert.False(t, set.ContainsKeys(-1))
+		assert.Panics(t, func() { set.Append(-1) })
+		assert.Panics(t, func() { set.Append(2, -1) })
+		assert.Equal(t, []int{1}, set.Slice(), "a batch with a negative key must not be partially applied")
+	})
+}
@@ -0,0 +1,199 @@
+package kset
+
+import (
+	"iter"
+	"sync"
+)
+
+// persistentMapStore is a hash array mapped trie whose nodes are never modified once created.
+// Mutations copy the path from the root, so clones share every node that was not modified since.
+type persistentMapStore[Key comparable, Value any] struct {
+	mutex sync.RWMutex
+	// writer serializes writes with Update transactions, which modify a private version of the store and publish it when done.
+	writer sync.Mutex
+	store  *hamt[Key, Value]
+}
+
+// PersistentHashMapKeyValue is a thread-safe persistent hash array mapped trie key-value set implementation.
+// Clone is O(1), and derived sets such as Union and Difference share all unmodified nodes with the receiver,
+// so keeping many versions of a large set only costs the entries that differ between them.
+// Iteration traverses an immutable snapshot, taken when the iteration starts.
+// Update modifies a private version of the set, published if fn succeeds, so readers are never blocked nor see it half-applied.
+//
+//	Operation		Average		WorstCase
+//	Search			O(log32N)	O(log32N)
+//	Insert			O(log32N)	O(log32N)
+//	Delete			O(log32N)	O(log32N)
+//	Clone			O(1)		O(1)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+//	Insert/Delete		O(log32N)	O(log32N)
+func PersistentHashMapKeyValue[Key comparable, Value any](selector func(Value) Key, values ...Value) KeyValueSet[Key, Value] {
+	data := newHamt[Key, Value]()
+	for i := range values {
+		data.Upsert(selector(values[i]), values[i])
+	}
+	return &keyValueSet[Key, Value, *persistentMapStore[Key, Value]]{
+		store: &persistentMapStore[Key, Value]{
+			store: data,
+		},
+		selector: selector,
+	}
+}
+
+// PersistentHashMapKey is a thread-safe persistent hash array mapped trie key set implementation.
+// Clone is O(1), and derived sets such as Union and Difference share all unmodified nodes with the receiver,
+// so keeping many versions of a large set only costs the keys that differ between them.
+// Iteration traverses an immutable snapshot, taken when the iteration starts.
+// Update modifies a private version of the set, published if fn succeeds, so readers are never blocked nor see it half-applied.
+//
+//	Operation		Average		WorstCase
+//	Search			O(log32N)	O(log32N)
+//	Insert			O(log32N)	O(log32N)
+//	Delete			O(log32N)	O(log32N)
+//	Clone			O(1)		O(1)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+//	Insert/Delete		O(log32N)	O(log32N)
+func PersistentHashMapKey[Key comparable](keys ...Key) KeySet[Key] {
+	data := newHamt[Key, empty]()
+	for _, key := range keys {
+		data.Upsert(key, empty{})
+	}
+	return &keySet[Key, *persistentMapStore[Key, empty]]{
+		store: &persistentMapStore[Key, empty]{
+			store: data,
+		},
+	}
+}
+
+// snapshot returns the current version of the trie, which is never modified afterwards.
+func (m *persistentMapStore[Key, Value]) snapshot() *hamt[Key, Value] {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.store.share()
+}
+
+func (m *persistentMapStore[Key, Value]) Clear() {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.store.Clear()
+}
+
+func (m *persistentMapStore[Key, Value]) Clone() Storage[Key, Value] {
+	return &persistentMapStore[Key, Value]{
+		store: m.snapshot(),
+	}
+}
+
+func (m *persistentMapStore[Key, Value]) Contains(key Key) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.store.Contains(key)
+}
+
+func (m *persistentMapStore[Key, Value]) Delete(keys ...Key) {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, key := range keys {
+		m.store.Delete(key)
+	}
+}
+
+func (m *persistentMapStore[Key, Value]) Get(key Key) (Value, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.store.Get(key)
+}
+
+func (m *persistentMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for key, value := range m.snapshot().All() {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+func (m *persistentMapStore[Key, Value]) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.store.Len()
+}
+
+func (m *persistentMapStore[Key, Value]) Upsert(key Key, value Value) {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.store.Upsert(key, value)
+}
+
+func (m *persistentMapStore[Key, Value]) compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old, found := m.store.Get(key)
+	return applyCompute(old, found, fn,
+		func(value Value) { m.store.Upsert(key, value) },
+		func() { m.store.Delete(key) },
+	)
+}
+
+func (m *persistentMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		inserted[i] = m.store.Upsert(key, valueAt(values, i))
+	}
+	return inserted
+}
+
+func (m *persistentMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		deleted[i] = m.store.Delete(key)
+	}
+	return deleted
+}
+
+// locked runs fn against a private version of the trie, published when fn returns,
+// so readers keep seeing the previous version in the meantime, while other writers wait for it.
+func (m *persistentMapStore[Key, Value]) locked(fn func(view Storage[Key, Value])) {
+	m.writer.Lock()
+	defer m.writer.Unlock()
+
+	view := &persistentMapStore[Key, Value]{store: m.snapshot()}
+	fn(storeView[Key, Value]{Storage: view, BatchStorage: view})
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.store = view.store
+}
+
+var (
+	_ Storage[string, string]          = &persistentMapStore[string, string]{}
+	_ BatchStorage[string, string]     = &persistentMapStore[string, string]{}
+	_ computingStorage[string, string] = &persistentMapStore[string, string]{}
+	_ lockingStorage[string, string]   = &persistentMapStore[string, string]{}
+)
@@ -104,6 +104,24 @@ func (m *safeMapStore[Key, Value]) Upsert(key Key, value Value) {
 	m.store[key] = value
 }
 
+func (m *safeMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return upsertMap(m.store, keys, values)
+}
+
+func (m *safeMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return deleteMap(m.store, keys)
+}
+
+func (m *safeMapStore[Key, Value]) locked(fn func(view Storage[Key, Value])) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	fn(&unsafeMapStore[Key, Value]{store: m.store})
+}
+
 func (m *safeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
 	return func(yield func(Key, Value) bool) {
 		m.mutex.RLock()
@@ -116,6 +134,10 @@ func (m *safeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
 	}
 }
 
+func (m *safeMapStore[Key, Value]) iterSnapshot() iter.Seq2[Key, Value] {
+	return lockedSnapshot(&m.mutex, maps.All(m.store))
+}
+
 func (m *safeMapStore[Key, Value]) Clone() Storage[Key, Value] {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
@@ -125,4 +147,8 @@ func (m *safeMapStore[Key, Value]) Clone() Storage[Key, Value] {
 	}
 }
 
-var _ Storage[string, string] = &safeMapStore[string, string]{}
+var (
+	_ Storage[string, string]        = &safeMapStore[string, string]{}
+	_ BatchStorage[string, string]   = &safeMapStore[string, string]{}
+	_ lockingStorage[string, string] = &safeMapStore[string, string]{}
+)
@@ -0,0 +1,355 @@
+package kset
+
+import (
+	"cmp"
+	"hash/maphash"
+	"iter"
+	"maps"
+	"math/bits"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// cacheLineSize is the size of the cache lines of common CPUs.
+const cacheLineSize = 64
+
+// shardedMapStore partitions keys across independently locked hash tables.
+// Operations on keys from different shards never contend for the same lock.
+type shardedMapStore[Key comparable, Value any] struct {
+	seed   maphash.Seed
+	shards []mapShard[Key, Value]
+	// count is updated while holding the lock of the modified shard, so Len does not lock every shard.
+	count atomic.Int64
+}
+
+type mapShard[Key comparable, Value any] struct {
+	mutex sync.RWMutex
+	store map[Key]Value
+	// Padding rounds the shard up to a multiple of the cache line size, so the locks of neighbouring shards
+	// never share a cache line, avoiding false sharing. Maps are pointers, so their size does not depend on Key and Value.
+	_ [cacheLineSize - (unsafe.Sizeof(sync.RWMutex{})+unsafe.Sizeof(map[int]int(nil)))%cacheLineSize]byte
+}
+
+// ShardedHashMapKeyValue is a thread-safe hash table key-value set implementation,
+// partitioning keys across independently locked shards to reduce lock contention under concurrent writes.
+// The shard count is rounded up to a power of two, and defaults to 4 times GOMAXPROCS when not positive.
+// Keys and KeyValues copy one shard at a time, so they are not an atomic snapshot under concurrent writes.
+// Update is not isolated: its changes are visible to other goroutines as fn makes them, and are undone one by one on error.
+// Pop scans the shards in order for a key, and retries if a concurrent writer removes it first.
+//
+//	Operation		Average		WorstCase
+//	Search			O(1)		O(logN^2)
+//	Insert			O(1)		O(logN^2)
+//	Delete			O(1)		O(n)
+//
+// Space complexity
+//
+//	Space			O(n+shards)	O(n+shards)
+func ShardedHashMapKeyValue[Key comparable, Value any](shards int, selector func(Value) Key, values ...Value) KeyValueSet[Key, Value] {
+	store := newShardedMapStore[Key, Value](shards)
+	store.UpsertMany(Select(selector, values...), values)
+
+	return &keyValueSet[Key, Value, *shardedMapStore[Key, Value]]{
+		store:    store,
+		selector: selector,
+	}
+}
+
+// ShardedHashMapKey is a thread-safe hash table key set implementation,
+// partitioning keys across independently locked shards to reduce lock contention under concurrent writes.
+// The shard count is rounded up to a power of two, and defaults to 4 times GOMAXPROCS when not positive.
+// Keys and KeyValues copy one shard at a time, so they are not an atomic snapshot under concurrent writes.
+// Update is not isolated: its changes are visible to other goroutines as fn makes them, and are undone one by one on error.
+// Pop scans the shards in order for a key, and retries if a concurrent writer removes it first.
+//
+//	Operation		Average		WorstCase
+//	Search			O(1)		O(logN^2)
+//	Insert			O(1)		O(logN^2)
+//	Delete			O(1)		O(n)
+//
+// Space complexity
+//
+//	Space			O(n+shards)	O(n+shards)
+func ShardedHashMapKey[Key comparable](shards int, keys ...Key) KeySet[Key] {
+	store := newShardedMapStore[Key, empty](shards)
+	store.UpsertMany(keys, nil)
+
+	return &keySet[Key, *shardedMapStore[Key, empty]]{
+		store: store,
+	}
+}
+
+func newShardedMapStore[Key comparable, Value any](shards int) *shardedMapStore[Key, Value] {
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	shards = 1 << bits.Len(uint(shards-1))
+
+	store := &shardedMapStore[Key, Value]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]mapShard[Key, Value], shards),
+	}
+	for i := range store.shards {
+		store.shards[i].store = make(map[Key]Value)
+	}
+	return store
+}
+
+// shardIndex returns the index of the shard responsible for the key.
+// The shard count is a power of two, so the hash is masked instead of divided.
+func (m *shardedMapStore[Key, Value]) shardIndex(key Key) int {
+	return int(maphash.Comparable(m.seed, key) & uint64(len(m.shards)-1))
+}
+
+func (m *shardedMapStore[Key, Value]) shard(key Key) *mapShard[Key, Value] {
+	return &m.shards[m.shardIndex(key)]
+}
+
+func (m *shardedMapStore[Key, Value]) Clear() {
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mutex.Lock()
+		m.count.Add(-int64(len(shard.store)))
+		clear(shard.store)
+		shard.mutex.Unlock()
+	}
+}
+
+func (m *shardedMapStore[Key, Value]) Contains(key Key) bool {
+	shard := m.shard(key)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	_, ok := shard.store[key]
+	return ok
+}
+
+func (m *shardedMapStore[Key, Value]) Delete(keys ...Key) {
+	if len(keys) == 1 {
+		shard := m.shard(keys[0])
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+
+		shard.delete(keys[0], &m.count)
+		return
+	}
+	m.DeleteMany(keys)
+}
+
+func (m *shardedMapStore[Key, Value]) Get(key Key) (Value, bool) {
+	shard := m.shard(key)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	value, ok := shard.store[key]
+	return value, ok
+}
+
+func (m *shardedMapStore[Key, Value]) Len() int {
+	return int(m.count.Load())
+}
+
+func (m *shardedMapStore[Key, Value]) Upsert(key Key, value Value) {
+	shard := m.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	shard.upsert(key, value, &m.count)
+}
+
+// upsert stores the value, incrementing count and returning true if the key is new.
+func (s *mapShard[Key, Value]) upsert(key Key, value Value, count *atomic.Int64) bool {
+	_, found := s.store[key]
+	if !found {
+		count.Add(1)
+	}
+	s.store[key] = value
+	return !found
+}
+
+// delete removes the key, decrementing count and returning true if it was found.
+func (s *mapShard[Key, Value]) delete(key Key, count *atomic.Int64) bool {
+	_, found := s.store[key]
+	if found {
+		delete(s.store, key)
+		count.Add(-1)
+	}
+	return found
+}
+
+// compute only locks the shard of the key.
+func (m *shardedMapStore[Key, Value]) compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	shard := m.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	_, existed := shard.store[key]
+	value, ok := computeStore(&unsafeMapStore[Key, Value]{store: shard.store}, key, fn)
+	switch {
+	case ok && !existed:
+		m.count.Add(1)
+	case !ok && existed:
+		m.count.Add(-1)
+	}
+	return value, ok
+}
+
+// UpsertMany sorts the entries by shard, and applies them while holding the lock of every shard involved.
+func (m *shardedMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	if len(keys) == 1 {
+		shard := m.shard(keys[0])
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+
+		return []bool{shard.upsert(keys[0], valueAt(values, 0), &m.count)}
+	}
+
+	inserted := make([]bool, len(keys))
+	batch := m.lockBatch(keys)
+	defer m.unlockBatch(batch)
+
+	added := 0
+	for _, i := range batch.order {
+		store := m.shards[batch.shards[i]].store
+		if _, ok := store[keys[i]]; !ok {
+			inserted[i] = true
+			added++
+		}
+		store[keys[i]] = valueAt(values, i)
+	}
+	m.count.Add(int64(added))
+	return inserted
+}
+
+// DeleteMany sorts the keys by shard, and deletes them while holding the lock of every shard involved.
+func (m *shardedMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	if len(keys) == 1 {
+		shard := m.shard(keys[0])
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+
+		return []bool{shard.delete(keys[0], &m.count)}
+	}
+
+	deleted := make([]bool, len(keys))
+	batch := m.lockBatch(keys)
+	defer m.unlockBatch(batch)
+
+	removed := 0
+	for _, i := range batch.order {
+		store := m.shards[batch.shards[i]].store
+		if _, ok := store[keys[i]]; ok {
+			delete(store, keys[i])
+			deleted[i] = true
+			removed++
+		}
+	}
+	m.count.Add(-int64(removed))
+	return deleted
+}
+
+// shardBatch holds the indexes of the keys of a batch sorted by shard, keeping their relative order within each shard.
+type shardBatch struct {
+	order []int
+	// shards holds the shard of each key of the batch.
+	shards []int
+}
+
+// lockBatch sorts the keys by shard, and locks the shards involved in ascending order,
+// so concurrent batches never wait on each other in a cycle.
+func (m *shardedMapStore[Key, Value]) lockBatch(keys []Key) shardBatch {
+	indexes := make([]int, 2*len(keys))
+	batch := shardBatch{order: indexes[:len(keys)], shards: indexes[len(keys):]}
+	for i, key := range keys {
+		batch.order[i] = i
+		batch.shards[i] = m.shardIndex(key)
+	}
+	slices.SortStableFunc(batch.order, func(a, b int) int {
+		return cmp.Compare(batch.shards[a], batch.shards[b])
+	})
+
+	for j, i := range batch.order {
+		if j == 0 || batch.shards[i] != batch.shards[batch.order[j-1]] {
+			m.shards[batch.shards[i]].mutex.Lock()
+		}
+	}
+	return batch
+}
+
+func (m *shardedMapStore[Key, Value]) unlockBatch(batch shardBatch) {
+	for j, i := range batch.order {
+		if j == 0 || batch.shards[i] != batch.shards[batch.order[j-1]] {
+			m.shards[batch.shards[i]].mutex.Unlock()
+		}
+	}
+}
+
+// Iter iterates through one shard at a time, only holding the lock of the shard being visited.
+func (m *shardedMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for i := range m.shards {
+			if !m.shards[i].iter(yield) {
+				return
+			}
+		}
+	}
+}
+
+func (s *mapShard[Key, Value]) iter(yield func(Key, Value) bool) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for key, value := range s.store {
+		if !yield(key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// iterSnapshot copies every shard before yielding, only holding the lock of the shard being copied.
+func (m *shardedMapStore[Key, Value]) iterSnapshot() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		keys := make([]Key, 0, m.Len())
+		values := make([]Value, 0, m.Len())
+		for i := range m.shards {
+			shard := &m.shards[i]
+			shard.mutex.RLock()
+			for key, value := range shard.store {
+				keys = append(keys, key)
+				values = append(values, value)
+			}
+			shard.mutex.RUnlock()
+		}
+
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (m *shardedMapStore[Key, Value]) Clone() Storage[Key, Value] {
+	clone := &shardedMapStore[Key, Value]{
+		seed:   m.seed,
+		shards: make([]mapShard[Key, Value], len(m.shards)),
+	}
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mutex.RLock()
+		clone.shards[i].store = maps.Clone(shard.store)
+		clone.count.Add(int64(len(shard.store)))
+		shard.mutex.RUnlock()
+	}
+	return clone
+}
+
+var (
+	_ Storage[string, string]          = &shardedMapStore[string, string]{}
+	_ BatchStorage[string, string]     = &shardedMapStore[string, string]{}
+	_ computingStorage[string, string] = &shardedMapStore[string, string]{}
+)
@@ -0,0 +1,106 @@
+package kset_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ShardedHashMapKey(t *testing.T) {
+	t.Run("shard padding", func(t *testing.T) {
+		assert.Zero(t, kset.MapShardSize%kset.CacheLineSize)
+	})
+
+	t.Run("shard count", func(t *testing.T) {
+		for _, shards := range []int{-1, 0, 1, 3, 64} {
+			set := kset.ShardedHashMapKey(shards, 1, 2, 3)
+			set.Append(4)
+			set.RemoveKeys(1)
+
+			assert.ElementsMatch(t, []int{2, 3, 4}, set.Slice(), "shards: %d", shards)
+		}
+	})
+
+	t.Run("concurrent append", func(t *testing.T) {
+		const workers, perWorker = 8, 1000
+
+		set := kset.ShardedHashMapKey[int](0)
+
+		var wg sync.WaitGroup
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perWorker {
+					set.Append(w*perWorker + i)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, workers*perWorker, set.Len())
+	})
+	t.Run("concurrent append counts", func(t *testing.T) {
+		const workers, keys = 8, 1000
+
+		set := kset.ShardedHashMapKey[int](4)
+
+		// Workers append overlapping batches, so each key must be counted as added by a single one.
+		var wg sync.WaitGroup
+		var added atomic.Int64
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range keys / 10 {
+					batch := make([]int, 0, 20)
+					for j := range 20 {
+						batch = append(batch, (w*7+i*10+j)%keys)
+					}
+					added.Add(int64(set.Append(batch...)))
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(set.Len()), added.Load())
+	})
+
+	t.Run("concurrent pop drains", func(t *testing.T) {
+		const workers, keys = 8, 1000
+
+		expected := make([]int, keys)
+		for i := range expected {
+			expected[i] = i
+		}
+		set := kset.ShardedHashMapKey(4, expected...)
+
+		// Every key must be popped exactly once.
+		var wg sync.WaitGroup
+		popped := make([][]int, workers)
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					key, ok := set.Pop()
+					if !ok {
+						return
+					}
+					popped[w] = append(popped[w], key)
+				}
+			}()
+		}
+		wg.Wait()
+
+		var all []int
+		for _, keys := range popped {
+			all = append(all, keys...)
+		}
+		assert.ElementsMatch(t, expected, all)
+		assert.True(t, set.IsEmpty())
+	})
+}
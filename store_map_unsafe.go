@@ -87,6 +87,37 @@ func (m *unsafeMapStore[Key, Value]) Upsert(key Key, value Value) {
 	m.store[key] = value
 }
 
+func (m *unsafeMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	return upsertMap(m.store, keys, values)
+}
+
+func (m *unsafeMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	return deleteMap(m.store, keys)
+}
+
+// upsertMap upserts the entries into the map, returning whether each key was inserted.
+func upsertMap[Key comparable, Value any](store map[Key]Value, keys []Key, values []Value) []bool {
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		_, found := store[key]
+		inserted[i] = !found
+		store[key] = valueAt(values, i)
+	}
+	return inserted
+}
+
+// deleteMap deletes the keys from the map, returning whether each key was found.
+func deleteMap[Key comparable, Value any](store map[Key]Value, keys []Key) []bool {
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		if _, found := store[key]; found {
+			delete(store, key)
+			deleted[i] = true
+		}
+	}
+	return deleted
+}
+
 func (m *unsafeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
 	return func(yield func(Key, Value) bool) {
 		for key, value := range m.store {
@@ -103,4 +134,7 @@ func (m *unsafeMapStore[Key, Value]) Clone() Storage[Key, Value] {
 	}
 }
 
-var _ Storage[string, string] = &unsafeMapStore[string, string]{}
+var (
+	_ Storage[string, string]      = &unsafeMapStore[string, string]{}
+	_ BatchStorage[string, string] = &unsafeMapStore[string, string]{}
+)
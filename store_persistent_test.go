@@ -0,0 +1,85 @@
+package kset_test
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Persistent(t *testing.T) {
+	constructors := map[string]func(keys ...int) kset.KeySet[int]{
+		"PersistentHashMapKey": kset.PersistentHashMapKey[int],
+		"PersistentTreeMapKey": func(keys ...int) kset.KeySet[int] { return kset.PersistentTreeMapKey(keys...) },
+		"CollidingPersistentHashMapKey": func(keys ...int) kset.KeySet[int] {
+			return kset.NewKeySet(kset.NewCollidingPersistentMapStore[struct{}](), keys...)
+		},
+	}
+
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			t.Run("versions", func(t *testing.T) {
+				rng := rand.New(rand.NewPCG(13, 14))
+
+				versions := []kset.KeySet[int]{constructor()}
+				expected := []map[int]struct{}{{}}
+
+				// Every version derives from a random previous one, so nodes end up shared between many versions.
+				for range 2000 {
+					i := rng.IntN(len(versions))
+					version := versions[i].Clone()
+					reference := maps.Clone(expected[i])
+
+					for range 1 + rng.IntN(5) {
+						key := rng.IntN(500)
+						if rng.IntN(3) == 0 {
+							version.RemoveKeys(key)
+							delete(reference, key)
+						} else {
+							version.Append(key)
+							reference[key] = struct{}{}
+						}
+					}
+
+					versions = append(versions, version)
+					expected = append(expected, reference)
+				}
+
+				for i, version := range versions {
+					require.ElementsMatch(t, slices.Collect(maps.Keys(expected[i])), version.Slice(), "version %d", i)
+				}
+			})
+
+			t.Run("derived sets", func(t *testing.T) {
+				a := constructor(1, 2, 3, 4)
+				b := constructor(3, 4, 5)
+
+				union := a.Union(b)
+				difference := a.Difference(b)
+				union.Append(10)
+				difference.RemoveKeys(1)
+
+				assert.ElementsMatch(t, []int{1, 2, 3, 4}, a.Slice())
+				assert.ElementsMatch(t, []int{3, 4, 5}, b.Slice())
+				assert.ElementsMatch(t, []int{1, 2, 3, 4, 5, 10}, union.Slice())
+				assert.ElementsMatch(t, []int{2}, difference.Slice())
+			})
+
+			t.Run("mutation during iteration", func(t *testing.T) {
+				set := constructor(1, 2, 3, 4)
+
+				for key := range set.Keys() {
+					if key%2 == 0 {
+						set.RemoveKeys(key)
+					}
+				}
+
+				assert.ElementsMatch(t, []int{1, 3}, set.Slice())
+			})
+		})
+	}
+}
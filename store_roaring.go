@@ -0,0 +1,277 @@
+package kset
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// roaringStore is a compressed bitmap, splitting keys in chunks of 2^16 by their upper 16 bits.
+// Each chunk is stored in the smallest container for its contents: a sorted array, a bitmap or a list of runs.
+type roaringStore[Key ~uint32] struct {
+	mutex      sync.RWMutex
+	keys       []uint16
+	containers []container
+}
+
+// RoaringKey is a thread-safe compressed bitmap key set implementation, for sparse 32-bit integer keys.
+// Keys are grouped in chunks of 65536, each stored as a sorted array, a bitmap or a list of runs, whichever is smaller.
+// Set operations between two roaring sets are computed container by container.
+// The set can be serialized in the portable roaring format with MarshalRoaring.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logC)		O(logC)
+//	Insert			O(logC)		O(C+4096)
+//	Delete			O(logC)		O(C+4096)
+//	Len			O(C)		O(C)
+//	Union/Intersect		O(C+n)		O(C+n)
+//
+// Where C is the number of chunks.
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func RoaringKey[Key ~uint32](keys ...Key) KeySet[Key] {
+	store := &roaringStore[Key]{}
+	store.UpsertMany(keys, nil)
+	store.optimize()
+
+	return &keySet[Key, *roaringStore[Key]]{
+		store: store,
+	}
+}
+
+// split returns the chunk and the value of the key inside the chunk.
+func split[Key ~uint32](key Key) (uint16, uint16) {
+	return uint16(key >> 16), uint16(key)
+}
+
+func (r *roaringStore[Key]) search(high uint16) (int, bool) {
+	return slices.BinarySearch(r.keys, high)
+}
+
+// upsert adds the key, returning whether it was missing.
+func (r *roaringStore[Key]) upsert(key Key) bool {
+	high, low := split(key)
+	i, found := r.search(high)
+	if !found {
+		r.keys = slices.Insert(r.keys, i, high)
+		r.containers = slices.Insert(r.containers, i, container(&arrayContainer{}))
+	}
+	var added bool
+	r.containers[i], added = r.containers[i].add(low)
+	return added
+}
+
+// remove deletes the key, dropping its container when empty. It returns whether the key was found.
+func (r *roaringStore[Key]) remove(key Key) bool {
+	high, low := split(key)
+	i, found := r.search(high)
+	if !found {
+		return false
+	}
+	var removed bool
+	r.containers[i], removed = r.containers[i].remove(low)
+	if r.containers[i].cardinality() == 0 {
+		r.keys = slices.Delete(r.keys, i, i+1)
+		r.containers = slices.Delete(r.containers, i, i+1)
+	}
+	return removed
+}
+
+// optimize converts every container to its smallest representation.
+func (r *roaringStore[Key]) optimize() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, c := range r.containers {
+		r.containers[i] = optimize(c)
+	}
+}
+
+func (r *roaringStore[Key]) Clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.keys = nil
+	r.containers = nil
+}
+
+func (r *roaringStore[Key]) Clone() Storage[Key, empty] {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	containers := make([]container, len(r.containers))
+	for i, c := range r.containers {
+		containers[i] = c.clone()
+	}
+
+	return &roaringStore[Key]{
+		keys:       slices.Clone(r.keys),
+		containers: containers,
+	}
+}
+
+func (r *roaringStore[Key]) Contains(key Key) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	high, low := split(key)
+	i, found := r.search(high)
+	return found && r.containers[i].contains(low)
+}
+
+func (r *roaringStore[Key]) Delete(keys ...Key) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, key := range keys {
+		r.remove(key)
+	}
+}
+
+func (r *roaringStore[Key]) DeleteMany(keys []Key) []bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		deleted[i] = r.remove(key)
+	}
+	return deleted
+}
+
+func (r *roaringStore[Key]) Get(key Key) (empty, bool) {
+	return empty{}, r.Contains(key)
+}
+
+// Iter iterates through the keys in ascending order.
+func (r *roaringStore[Key]) Iter() iter.Seq2[Key, empty] {
+	return func(yield func(Key, empty) bool) {
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+		r.iterContainers(yield)
+	}
+}
+
+// iterSnapshot iterates through a clone, as containers are copied faster than their keys are collected.
+func (r *roaringStore[Key]) iterSnapshot() iter.Seq2[Key, empty] {
+	return func(yield func(Key, empty) bool) {
+		r.Clone().(*roaringStore[Key]).iterContainers(yield)
+	}
+}
+
+func (r *roaringStore[Key]) iterContainers(yield func(Key, empty) bool) {
+	for i, c := range r.containers {
+		high := Key(r.keys[i]) << 16
+		for low := range c.all() {
+			if !yield(high|Key(low), empty{}) {
+				return
+			}
+		}
+	}
+}
+
+// Len sums the cardinality of every container.
+func (r *roaringStore[Key]) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, c := range r.containers {
+		count += c.cardinality()
+	}
+	return count
+}
+
+func (r *roaringStore[Key]) Upsert(key Key, _ empty) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.upsert(key)
+}
+
+// UpsertMany inserts the keys in ascending order, so each container is filled in ascending order.
+func (r *roaringStore[Key]) UpsertMany(keys []Key, _ []empty) []bool {
+	order := ascendingOrder(keys, func(a, b Key) int {
+		return cmp.Compare(a, b)
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	inserted := make([]bool, len(keys))
+	for _, i := range order {
+		inserted[i] = r.upsert(keys[i])
+	}
+	return inserted
+}
+
+// combine computes the operation container by container, only combining the containers present in both sets.
+// The locks of both sets are never held at the same time.
+// locked runs fn against a view sharing the containers of the store, while holding its write lock.
+// The store adopts the containers of the view afterwards, as the view might have reallocated them.
+func (r *roaringStore[Key]) locked(fn func(view Storage[Key, empty])) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	view := &roaringStore[Key]{keys: r.keys, containers: r.containers}
+	defer func() { r.keys, r.containers = view.keys, view.containers }()
+	fn(storeView[Key, empty]{Storage: view, BatchStorage: view})
+}
+
+func (r *roaringStore[Key]) combine(other *roaringStore[Key], op mergeOp) *roaringStore[Key] {
+	a := r.Clone().(*roaringStore[Key])
+
+	other.mutex.RLock()
+	defer other.mutex.RUnlock()
+
+	result := &roaringStore[Key]{}
+	appendContainer := func(high uint16, c container) {
+		if c != nil {
+			result.keys = append(result.keys, high)
+			result.containers = append(result.containers, c)
+		}
+	}
+
+	keepA := op != mergeIntersection
+	keepB := op == mergeUnion || op == mergeSymmetricDifference
+
+	i, j := 0, 0
+	for i < len(a.keys) && j < len(other.keys) {
+		switch {
+		case a.keys[i] < other.keys[j]:
+			if keepA {
+				appendContainer(a.keys[i], a.containers[i])
+			}
+			i++
+		case a.keys[i] > other.keys[j]:
+			if keepB {
+				appendContainer(other.keys[j], other.containers[j].clone())
+			}
+			j++
+		default:
+			appendContainer(a.keys[i], combineContainers(a.containers[i], other.containers[j], op))
+			i++
+			j++
+		}
+	}
+
+	for ; keepA && i < len(a.keys); i++ {
+		appendContainer(a.keys[i], a.containers[i])
+	}
+
+	for ; keepB && j < len(other.keys); j++ {
+		appendContainer(other.keys[j], other.containers[j].clone())
+	}
+
+	return result
+}
+
+var (
+	_ Storage[uint32, empty]                   = &roaringStore[uint32]{}
+	_ BatchStorage[uint32, empty]              = &roaringStore[uint32]{}
+	_ combinableStorage[*roaringStore[uint32]] = &roaringStore[uint32]{}
+	_ lockingStorage[uint32, empty]            = &roaringStore[uint32]{}
+)
@@ -0,0 +1,133 @@
+package kset_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/sonalys/kset/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roaringKeys generates keys exercising all container types: sparse arrays, dense bitmaps and consecutive runs.
+func roaringKeys(rng *rand.Rand) []uint32 {
+	keys := make([]uint32, 0, 30_000)
+	for range 1000 {
+		keys = append(keys, rng.Uint32())
+	}
+	for range 10_000 {
+		keys = append(keys, 1<<16+uint32(rng.IntN(1<<16)))
+	}
+	for i := range uint32(15_000) {
+		keys = append(keys, 5<<16+i)
+	}
+	return keys
+}
+
+func Test_RoaringKey(t *testing.T) {
+	t.Run("storage", func(t *testing.T) {
+		entry := func(i int) (uint32, struct{}) { return uint32(i) * 40_009, struct{}{} }
+		storagetest.Run(t, kset.NewRoaringStore[uint32], entry)
+		storagetest.RunConcurrent(t, kset.NewRoaringStore[uint32], entry)
+	})
+
+	t.Run("equivalence", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(7, 8))
+		keys := roaringKeys(rng)
+
+		set := kset.RoaringKey(keys...)
+		expected := kset.HashMapKey(keys...)
+		require.Equal(t, expected.Len(), set.Len())
+
+		for range 50_000 {
+			key := keys[rng.IntN(len(keys))] + uint32(rng.IntN(3))
+			if rng.IntN(2) == 0 {
+				set.RemoveKeys(key)
+				expected.RemoveKeys(key)
+			} else {
+				assert.Equal(t, expected.Append(key), set.Append(key))
+			}
+			assert.Equal(t, expected.ContainsKeys(key), set.ContainsKeys(key))
+		}
+
+		assert.Equal(t, expected.Len(), set.Len())
+		assert.True(t, set.Equal(expected))
+		assert.IsIncreasing(t, set.Slice())
+	})
+
+	t.Run("algebra", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(9, 10))
+		aKeys, bKeys := roaringKeys(rng), roaringKeys(rng)
+
+		a, b := kset.RoaringKey(aKeys...), kset.RoaringKey(bKeys...)
+		hashA, hashB := kset.HashMapKey(aKeys...), kset.HashMapKey(bKeys...)
+
+		assert.True(t, a.Union(b).Equal(hashA.Union(hashB)))
+		assert.True(t, a.Intersect(b).Equal(hashA.Intersect(hashB)))
+		assert.True(t, a.Difference(b).Equal(hashA.Difference(hashB)))
+		assert.True(t, a.SymmetricDifference(b).Equal(hashA.SymmetricDifference(hashB)))
+		assert.True(t, a.Union(hashB).Equal(hashA.Union(hashB)))
+
+		// Derived sets must not share containers with their operands.
+		union := a.Union(b)
+		union.Clear()
+		assert.Equal(t, hashA.Len(), a.Len())
+		assert.Equal(t, hashB.Len(), b.Len())
+	})
+
+	t.Run("format", func(t *testing.T) {
+		arrays := []byte{
+			0x3A, 0x30, 0x00, 0x00, // cookie without runs
+			0x01, 0x00, 0x00, 0x00, // 1 container
+			0x00, 0x00, 0x02, 0x00, // key 0, cardinality 3
+			0x10, 0x00, 0x00, 0x00, // offset 16
+			0x01, 0x00, 0x02, 0x00, 0x05, 0x00, // values
+		}
+		assert.Equal(t, arrays, kset.MarshalRoaring(kset.RoaringKey[uint32](1, 2, 5)))
+
+		runs := []byte{
+			0x3B, 0x30, 0x00, 0x00, // cookie with runs, 1 container
+			0x01,                   // run flags
+			0x02, 0x00, 0x63, 0x00, // key 2, cardinality 100
+			0x01, 0x00, // 1 run
+			0x0A, 0x00, 0x63, 0x00, // start 10, length 100
+		}
+		keys := make([]uint32, 0, 100)
+		for i := range uint32(100) {
+			keys = append(keys, 2<<16+10+i)
+		}
+		assert.Equal(t, runs, kset.MarshalRoaring(kset.HashMapKey(keys...)))
+
+		set, err := kset.UnmarshalRoaring[uint32](runs)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, keys, set.Slice())
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		rng := rand.New(rand.NewPCG(11, 12))
+		set := kset.RoaringKey(roaringKeys(rng)...)
+
+		got, err := kset.UnmarshalRoaring[uint32](kset.MarshalRoaring(set))
+		require.NoError(t, err)
+		assert.True(t, set.Equal(got))
+
+		empty, err := kset.UnmarshalRoaring[uint32](kset.MarshalRoaring(kset.RoaringKey[uint32]()))
+		require.NoError(t, err)
+		assert.Zero(t, empty.Len())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		data := kset.MarshalRoaring(kset.RoaringKey[uint32](1, 2, 5))
+
+		for _, invalid := range [][]byte{
+			nil,
+			{0x00, 0x00, 0x00, 0x00},
+			data[:len(data)-1],
+			append(data[:16:16], 0x02, 0x00, 0x01, 0x00, 0x05, 0x00),
+		} {
+			_, err := kset.UnmarshalRoaring[uint32](invalid)
+			assert.ErrorIs(t, err, kset.ErrInvalidRoaring)
+		}
+	})
+}
@@ -0,0 +1,453 @@
+package kset
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+// sortedSliceStore keeps keys and values in two parallel slices, sorted by key.
+// For key sets, the values slice has zero-sized elements and takes no memory.
+type sortedSliceStore[Key, Value any] struct {
+	mutex  sync.RWMutex
+	keys   []Key
+	values []Value
+	cmp    func(a, b Key) int
+}
+
+// SortedSliceKeyValue is a thread-safe sorted slice key-value set implementation.
+// It is optimized for read-mostly sets: lookups are binary searches over contiguous memory,
+// and appending many values at once sorts and merges them in a single pass.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(n)		O(n)
+//	Bulk Insert		O(n+mlogm)	O(n+mlogm)
+//	Delete			O(n)		O(n)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func SortedSliceKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	store := newSortedSliceStore[Key, Value](cmp.Compare[Key])
+	store.UpsertMany(Select(selector, values...), values)
+
+	return &orderedKeyValueSet[Key, Value, *sortedSliceStore[Key, Value]]{
+		keyValueSet: &keyValueSet[Key, Value, *sortedSliceStore[Key, Value]]{
+			store:    store,
+			selector: selector,
+		},
+	}
+}
+
+// SortedSliceKey is a thread-safe sorted slice key set implementation.
+// It is optimized for read-mostly sets: lookups are binary searches over contiguous memory,
+// and appending many keys at once sorts and merges them in a single pass.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(n)		O(n)
+//	Bulk Insert		O(n+mlogm)	O(n+mlogm)
+//	Delete			O(n)		O(n)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func SortedSliceKey[Key constraints.Ordered](keys ...Key) OrderedKeySet[Key] {
+	store := newSortedSliceStore[Key, empty](cmp.Compare[Key])
+	store.UpsertMany(keys, nil)
+
+	return &orderedKeySet[Key, *sortedSliceStore[Key, empty]]{
+		keySet: &keySet[Key, *sortedSliceStore[Key, empty]]{
+			store: store,
+		},
+	}
+}
+
+func newSortedSliceStore[Key, Value any](cmp func(a, b Key) int) *sortedSliceStore[Key, Value] {
+	return &sortedSliceStore[Key, Value]{
+		cmp: cmp,
+	}
+}
+
+// search returns the position of the first key not less than the given key, and whether it is equal.
+func (s *sortedSliceStore[Key, Value]) search(key Key) (int, bool) {
+	return slices.BinarySearchFunc(s.keys, key, s.cmp)
+}
+
+// entry returns the entry at the given position, or false if out of bounds.
+func (s *sortedSliceStore[Key, Value]) entry(i int) (Key, Value, bool) {
+	if i < 0 || i >= len(s.keys) {
+		var key Key
+		var value Value
+		return key, value, false
+	}
+	return s.keys[i], s.values[i], true
+}
+
+func (s *sortedSliceStore[Key, Value]) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	clear(s.keys)
+	clear(s.values)
+	s.keys = s.keys[:0]
+	s.values = s.values[:0]
+}
+
+func (s *sortedSliceStore[Key, Value]) Clone() Storage[Key, Value] {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return &sortedSliceStore[Key, Value]{
+		keys:   slices.Clone(s.keys),
+		values: slices.Clone(s.values),
+		cmp:    s.cmp,
+	}
+}
+
+func (s *sortedSliceStore[Key, Value]) Contains(key Key) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, found := s.search(key)
+	return found
+}
+
+func (s *sortedSliceStore[Key, Value]) Delete(keys ...Key) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.delete(keys)
+}
+
+func (s *sortedSliceStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.delete(keys)
+}
+
+// delete removes all the given keys, compacting the slices in a single pass.
+func (s *sortedSliceStore[Key, Value]) delete(keys []Key) []bool {
+	deleted := make([]bool, len(keys))
+
+	// Each hit is the position of a found key, and the index of the key in the batch.
+	hits := make([][2]int, 0, len(keys))
+	for j, key := range keys {
+		if i, found := s.search(key); found {
+			hits = append(hits, [2]int{i, j})
+		}
+	}
+	if len(hits) == 0 {
+		return deleted
+	}
+
+	// Only the first of the repeated keys is deleted, the following ones are already missing.
+	slices.SortFunc(hits, func(a, b [2]int) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	positions := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		if len(positions) == 0 || positions[len(positions)-1] != hit[0] {
+			positions = append(positions, hit[0])
+			deleted[hit[1]] = true
+		}
+	}
+
+	write := positions[0]
+	for p, read := range positions {
+		end := len(s.keys)
+		if p+1 < len(positions) {
+			end = positions[p+1]
+		}
+		copy(s.values[write:], s.values[read+1:end])
+		write += copy(s.keys[write:], s.keys[read+1:end])
+	}
+
+	clear(s.keys[write:])
+	clear(s.values[write:])
+	s.keys = s.keys[:write]
+	s.values = s.values[:write]
+	return deleted
+}
+
+func (s *sortedSliceStore[Key, Value]) Get(key Key) (Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if i, found := s.search(key); found {
+		return s.values[i], true
+	}
+	var zero Value
+	return zero, false
+}
+
+func (s *sortedSliceStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+		for i := range s.keys {
+			if !yield(s.keys[i], s.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (s *sortedSliceStore[Key, Value]) iterSnapshot() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		keys, values := s.snapshot()
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot copies the entries, so they can be yielded without holding the lock.
+func (s *sortedSliceStore[Key, Value]) snapshot() ([]Key, []Value) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.keys), slices.Clone(s.values)
+}
+
+func (s *sortedSliceStore[Key, Value]) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.keys)
+}
+
+func (s *sortedSliceStore[Key, Value]) Upsert(key Key, value Value) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i, found := s.search(key)
+	if found {
+		s.values[i] = value
+		return
+	}
+	s.keys = slices.Insert(s.keys, i, key)
+	s.values = slices.Insert(s.values, i, value)
+}
+
+func (s *sortedSliceStore[Key, Value]) compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var old Value
+	i, found := s.search(key)
+	if found {
+		old = s.values[i]
+	}
+	return applyCompute(old, found, fn,
+		func(value Value) {
+			if found {
+				s.values[i] = value
+				return
+			}
+			s.keys = slices.Insert(s.keys, i, key)
+			s.values = slices.Insert(s.values, i, value)
+		},
+		func() {
+			s.keys = slices.Delete(s.keys, i, i+1)
+			s.values = slices.Delete(s.values, i, i+1)
+		},
+	)
+}
+
+// UpsertMany sorts the given entries and merges them with the stored ones in a single pass.
+// When a key is repeated, the last value is kept.
+func (s *sortedSliceStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	inserted := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return inserted
+	}
+
+	order := ascendingOrder(keys, s.cmp)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var mergedKeys []Key
+	var mergedValues []Value
+
+	i := 0
+	if len(s.keys) == 0 || s.cmp(s.keys[len(s.keys)-1], keys[order[0]]) < 0 {
+		// All keys go after the stored ones, such as when restoring ascending chunks, so they are appended in place.
+		mergedKeys, mergedValues, i = s.keys, s.values, len(s.keys)
+	} else {
+		mergedKeys = make([]Key, 0, len(s.keys)+len(keys))
+		mergedValues = make([]Value, 0, len(s.keys)+len(keys))
+	}
+
+	// Repeated keys are adjacent in order, from first to last upserted.
+	first := 0
+	for j := 0; j < len(order); j++ {
+		if j+1 < len(order) && s.cmp(keys[order[j]], keys[order[j+1]]) == 0 {
+			continue
+		}
+		key := keys[order[j]]
+
+		for i < len(s.keys) && s.cmp(s.keys[i], key) < 0 {
+			mergedKeys = append(mergedKeys, s.keys[i])
+			mergedValues = append(mergedValues, s.values[i])
+			i++
+		}
+		found := i < len(s.keys) && s.cmp(s.keys[i], key) == 0
+		if found {
+			i++
+		}
+		inserted[order[first]] = !found
+		first = j + 1
+
+		mergedKeys = append(mergedKeys, key)
+		mergedValues = append(mergedValues, valueAt(values, order[j]))
+	}
+
+	s.keys = append(mergedKeys, s.keys[i:]...)
+	s.values = append(mergedValues, s.values[i:]...)
+	return inserted
+}
+
+// locked runs fn against a view sharing the slices of the store, while holding its write lock.
+// The store adopts the slices of the view afterwards, as the view might have reallocated them.
+func (s *sortedSliceStore[Key, Value]) locked(fn func(view Storage[Key, Value])) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	view := &sortedSliceStore[Key, Value]{keys: s.keys, values: s.values, cmp: s.cmp}
+	defer func() { s.keys, s.values = view.keys, view.values }()
+	fn(storeView[Key, Value]{Storage: view, BatchStorage: view})
+}
+
+func (s *sortedSliceStore[Key, Value]) Min() (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.entry(0)
+}
+
+func (s *sortedSliceStore[Key, Value]) Max() (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.entry(len(s.keys) - 1)
+}
+
+func (s *sortedSliceStore[Key, Value]) Floor(key Key) (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, found := s.search(key)
+	if found {
+		return s.entry(i)
+	}
+	return s.entry(i - 1)
+}
+
+func (s *sortedSliceStore[Key, Value]) Ceiling(key Key) (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, _ := s.search(key)
+	return s.entry(i)
+}
+
+func (s *sortedSliceStore[Key, Value]) Lower(key Key) (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, _ := s.search(key)
+	return s.entry(i - 1)
+}
+
+func (s *sortedSliceStore[Key, Value]) Higher(key Key) (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, found := s.search(key)
+	if found {
+		return s.entry(i + 1)
+	}
+	return s.entry(i)
+}
+
+func (s *sortedSliceStore[Key, Value]) Rank(key Key) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, _ := s.search(key)
+	return i
+}
+
+func (s *sortedSliceStore[Key, Value]) At(i int) (Key, Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.entry(i)
+}
+
+func (s *sortedSliceStore[Key, Value]) Range(from, to Key) iter.Seq2[Key, Value] {
+	return lockedChunks(&s.mutex, func(after *Key) iter.Seq2[Key, Value] {
+		start, _ := s.search(from)
+		if after != nil {
+			i, found := s.search(*after)
+			if found {
+				i++
+			}
+			start = i
+		}
+		end, _ := s.search(to)
+		return s.ascend(start, max(start, end))
+	})
+}
+
+func (s *sortedSliceStore[Key, Value]) Backward() iter.Seq2[Key, Value] {
+	return lockedChunks(&s.mutex, func(before *Key) iter.Seq2[Key, Value] {
+		end := len(s.keys)
+		if before != nil {
+			end, _ = s.search(*before)
+		}
+		return func(yield func(Key, Value) bool) {
+			for i := end - 1; i >= 0; i-- {
+				if !yield(s.keys[i], s.values[i]) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// ascend iterates through the entries at positions in [start, end).
+func (s *sortedSliceStore[Key, Value]) ascend(start, end int) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for i := start; i < end; i++ {
+			if !yield(s.keys[i], s.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (s *sortedSliceStore[Key, Value]) compare(a, b Key) int {
+	return s.cmp(a, b)
+}
+
+func (s *sortedSliceStore[Key, Value]) loadSorted(keys []Key, values []Value) Storage[Key, Value] {
+	if values == nil {
+		values = make([]Value, len(keys))
+	}
+	return &sortedSliceStore[Key, Value]{
+		keys:   keys,
+		values: values,
+		cmp:    s.cmp,
+	}
+}
+
+var (
+	_ sortedStorage[string, string]    = &sortedSliceStore[string, string]{}
+	_ computingStorage[string, string] = &sortedSliceStore[string, string]{}
+	_ lockingStorage[string, string]   = &sortedSliceStore[string, string]{}
+)
@@ -0,0 +1,112 @@
+package kset_test
+
+import (
+	"iter"
+	"maps"
+	"strconv"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/sonalys/kset/storagetest"
+)
+
+// customStore is a minimal user-provided storage, used to validate custom backends.
+// It has no batch operations, so sets apply batches to it one by one.
+type customStore[Key comparable, Value any] struct {
+	data map[Key]Value
+}
+
+func newCustomStore[Key comparable, Value any]() *customStore[Key, Value] {
+	return &customStore[Key, Value]{
+		data: make(map[Key]Value),
+	}
+}
+
+func (c *customStore[Key, Value]) Len() int {
+	return len(c.data)
+}
+
+func (c *customStore[Key, Value]) Clear() {
+	clear(c.data)
+}
+
+func (c *customStore[Key, Value]) Delete(keys ...Key) {
+	for _, key := range keys {
+		delete(c.data, key)
+	}
+}
+
+func (c *customStore[Key, Value]) Contains(key Key) bool {
+	_, ok := c.data[key]
+	return ok
+}
+
+func (c *customStore[Key, Value]) Get(key Key) (Value, bool) {
+	value, ok := c.data[key]
+	return value, ok
+}
+
+func (c *customStore[Key, Value]) Upsert(key Key, value Value) {
+	c.data[key] = value
+}
+
+func (c *customStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
+	return maps.All(c.data)
+}
+
+func (c *customStore[Key, Value]) Clone() kset.Storage[Key, Value] {
+	return &customStore[Key, Value]{
+		data: maps.Clone(c.data),
+	}
+}
+
+func testEntry(i int) (int, string) {
+	return i, strconv.Itoa(i)
+}
+
+func Test_Storage(t *testing.T) {
+	t.Run("safeMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewSafeMapStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewSafeMapStore[int, string], testEntry)
+	})
+
+	t.Run("unsafeMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewUnsafeMapStore[int, string], testEntry)
+	})
+
+	t.Run("treeMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewTreeMapStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewTreeMapStore[int, string], testEntry)
+	})
+
+	t.Run("unsafeTreeMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewUnsafeTreeMapStore[int, string], testEntry)
+	})
+
+	t.Run("sortedSliceStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewSortedSliceStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewSortedSliceStore[int, string], testEntry)
+	})
+
+	t.Run("shardedMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewShardedMapStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewShardedMapStore[int, string], testEntry)
+	})
+
+	t.Run("persistentMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewPersistentMapStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewPersistentMapStore[int, string], testEntry)
+		storagetest.Run(t, kset.NewCollidingPersistentMapStore[string], testEntry)
+	})
+
+	t.Run("persistentTreeMapStore", func(t *testing.T) {
+		storagetest.Run(t, kset.NewPersistentTreeMapStore[int, string], testEntry)
+		storagetest.RunConcurrent(t, kset.NewPersistentTreeMapStore[int, string], testEntry)
+	})
+
+	t.Run("customStore", func(t *testing.T) {
+		storagetest.Run(t, func() kset.Storage[int, string] {
+			return newCustomStore[int, string]()
+		}, testEntry)
+	})
+}
@@ -1,63 +1,108 @@
 package kset
 
 import (
+	"cmp"
 	"iter"
 	"sync"
 
-	"github.com/igrmk/treemap/v2"
 	"golang.org/x/exp/constraints"
 )
 
-type treeMapStore[Key constraints.Ordered, Value any] struct {
+type treeMapStore[Key, Value any] struct {
 	mutex sync.RWMutex
-	store *treemap.TreeMap[Key, Value]
+	store *tree[Key, Value]
 }
 
-// TreeMapKeyValue is a thread-safe red-black tree key-value set implementation.
+// TreeMapKeyValue is a thread-safe AVL tree key-value set implementation.
+// The tree is augmented with subtree sizes, providing order statistics through Rank and At.
 //
 //	Operation		Average		WorstCase
 //	Search			O(logN)		O(logN)
 //	Insert			O(logN)		O(logN)
 //	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
 //
 // Space complexity
 //
 //	Space			O(n)		O(n)
-func TreeMapKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) KeyValueSet[Key, Value] {
-	data := treemap.New[Key, Value]()
+func TreeMapKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	return TreeMapKeyValueFunc(cmp.Compare[Key], selector, values...)
+}
+
+// TreeMapKeyValueFunc is a thread-safe AVL tree key-value set implementation, sorted by the given comparison function.
+// compare must return a negative number when a < b, a positive number when a > b and zero when a == b,
+// following the conventions of cmp.Compare and slices.SortFunc.
+// Keys are considered equal when compare returns zero.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func TreeMapKeyValueFunc[Key comparable, Value any](compare func(a, b Key) int, selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	data := newTree[Key, Value](compare)
 
 	for i := range values {
-		data.Set(selector(values[i]), values[i])
+		data.Upsert(selector(values[i]), values[i])
 	}
 
-	return &keyValueSet[Key, Value, *treeMapStore[Key, Value]]{
-		store: &treeMapStore[Key, Value]{
-			store: data,
+	return &orderedKeyValueSet[Key, Value, *treeMapStore[Key, Value]]{
+		keyValueSet: &keyValueSet[Key, Value, *treeMapStore[Key, Value]]{
+			store: &treeMapStore[Key, Value]{
+				store: data,
+			},
+			selector: selector,
 		},
-		selector: selector,
 	}
 }
 
-// TreeMapKey is a thread-safe red-black tree key set implementation.
+// TreeMapKey is a thread-safe AVL tree key set implementation.
+// The tree is augmented with subtree sizes, providing order statistics through Rank and At.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func TreeMapKey[Key constraints.Ordered](keys ...Key) OrderedKeySet[Key] {
+	return TreeMapKeyFunc(cmp.Compare[Key], keys...)
+}
+
+// TreeMapKeyFunc is a thread-safe AVL tree key set implementation, sorted by the given comparison function.
+// It allows keys that are not ordered by the language, such as structs and time.Time, to be kept sorted.
+// compare must return a negative number when a < b, a positive number when a > b and zero when a == b,
+// following the conventions of cmp.Compare and slices.SortFunc.
+// Keys are considered equal when compare returns zero.
 //
 //	Operation		Average		WorstCase
 //	Search			O(logN)		O(logN)
 //	Insert			O(logN)		O(logN)
 //	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
 //
 // Space complexity
 //
 //	Space			O(n)		O(n)
-func TreeMapKey[Key constraints.Ordered](keys ...Key) KeySet[Key] {
-	data := treemap.New[Key, empty]()
+func TreeMapKeyFunc[Key any](compare func(a, b Key) int, keys ...Key) OrderedKeySet[Key] {
+	data := newTree[Key, empty](compare)
 
 	for _, key := range keys {
-		data.Set(key, empty{})
+		data.Upsert(key, empty{})
 	}
 
-	return &keySet[Key, *treeMapStore[Key, empty]]{
-		store: &treeMapStore[Key, empty]{
-			store: data,
+	return &orderedKeySet[Key, *treeMapStore[Key, empty]]{
+		keySet: &keySet[Key, *treeMapStore[Key, empty]]{
+			store: &treeMapStore[Key, empty]{
+				store: data,
+			},
 		},
 	}
 }
@@ -70,17 +115,11 @@ func (t *treeMapStore[Key, Value]) Clear() {
 }
 
 func (t *treeMapStore[Key, Value]) Clone() Storage[Key, Value] {
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
-
-	clone := treemap.New[Key, Value]()
-
-	for iter := t.store.Iterator(); iter.Valid(); iter.Next() {
-		clone.Set(iter.Key(), iter.Value())
-	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
 
 	return &treeMapStore[Key, Value]{
-		store: clone,
+		store: t.store.Clone(),
 	}
 }
 
@@ -95,7 +134,7 @@ func (t *treeMapStore[Key, Value]) Delete(keys ...Key) {
 	defer t.mutex.Unlock()
 
 	for _, key := range keys {
-		t.store.Del(key)
+		t.store.Delete(key)
 	}
 }
 
@@ -105,18 +144,28 @@ func (t *treeMapStore[Key, Value]) Get(key Key) (Value, bool) {
 	return t.store.Get(key)
 }
 
+func (t *treeMapStore[Key, Value]) locked(fn func(view Storage[Key, Value])) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fn(&unsafeTreeMapStore[Key, Value]{store: t.store})
+}
+
 func (t *treeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
 	return func(yield func(Key, Value) bool) {
 		t.mutex.RLock()
 		defer t.mutex.RUnlock()
-		for iter := t.store.Iterator(); iter.Valid(); iter.Next() {
-			if !yield(iter.Key(), iter.Value()) {
+		for key, value := range t.store.Ascend(nil, nil) {
+			if !yield(key, value) {
 				return
 			}
 		}
 	}
 }
 
+func (t *treeMapStore[Key, Value]) iterSnapshot() iter.Seq2[Key, Value] {
+	return lockedSnapshot(&t.mutex, t.store.Ascend(nil, nil))
+}
+
 func (t *treeMapStore[Key, Value]) Len() int {
 	t.mutex.RLock()
 	defer t.mutex.RUnlock()
@@ -126,7 +175,97 @@ func (t *treeMapStore[Key, Value]) Len() int {
 func (t *treeMapStore[Key, Value]) Upsert(key Key, value Value) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
-	t.store.Set(key, value)
+	t.store.Upsert(key, value)
+}
+
+func (t *treeMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.store.UpsertMany(keys, values)
+}
+
+func (t *treeMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.store.DeleteMany(keys)
+}
+
+func (t *treeMapStore[Key, Value]) Min() (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Min())
+}
+
+func (t *treeMapStore[Key, Value]) Max() (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Max())
+}
+
+func (t *treeMapStore[Key, Value]) Floor(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Floor(key))
+}
+
+func (t *treeMapStore[Key, Value]) Ceiling(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Ceiling(key))
+}
+
+func (t *treeMapStore[Key, Value]) Lower(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Lower(key))
+}
+
+func (t *treeMapStore[Key, Value]) Higher(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Higher(key))
+}
+
+func (t *treeMapStore[Key, Value]) Rank(key Key) int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.Rank(key)
+}
+
+func (t *treeMapStore[Key, Value]) At(i int) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.At(i))
+}
+
+func (t *treeMapStore[Key, Value]) Range(from, to Key) iter.Seq2[Key, Value] {
+	return lockedChunks(&t.mutex, func(after *Key) iter.Seq2[Key, Value] {
+		if after == nil {
+			return t.store.Ascend(&from, &to)
+		}
+		next := t.store.Higher(*after)
+		if next == nil {
+			return func(func(Key, Value) bool) {}
+		}
+		return t.store.Ascend(&next.key, &to)
+	})
+}
+
+func (t *treeMapStore[Key, Value]) Backward() iter.Seq2[Key, Value] {
+	return lockedChunks(&t.mutex, t.store.Descend)
+}
+
+func (t *treeMapStore[Key, Value]) compare(a, b Key) int {
+	return t.store.cmp(a, b)
 }
 
-var _ Storage[string, string] = &treeMapStore[string, string]{}
+func (t *treeMapStore[Key, Value]) loadSorted(keys []Key, values []Value) Storage[Key, Value] {
+	return &treeMapStore[Key, Value]{
+		store: t.store.loadSorted(keys, values),
+	}
+}
+
+var (
+	_ sortedStorage[string, string]  = &treeMapStore[string, string]{}
+	_ lockingStorage[string, string] = &treeMapStore[string, string]{}
+)
@@ -0,0 +1,279 @@
+package kset
+
+import (
+	"cmp"
+	"iter"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+// persistentTreeMapStore is an AVL tree whose nodes are never modified once shared.
+// Mutations copy the path from the root, so clones share every node that was not modified since.
+type persistentTreeMapStore[Key, Value any] struct {
+	mutex sync.RWMutex
+	// writer serializes writes with Update transactions, which modify a private version of the store and publish it when done.
+	writer sync.Mutex
+	store  *tree[Key, Value]
+}
+
+// PersistentTreeMapKeyValue is a thread-safe persistent AVL tree key-value set implementation.
+// Clone is O(1), and derived sets such as Union and Difference share all unmodified nodes with the receiver,
+// so keeping many versions of a large set only costs the entries that differ between them.
+// Iteration traverses an immutable snapshot, taken when the iteration starts.
+// Update modifies a private version of the set, published if fn succeeds, so readers are never blocked nor see it half-applied.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Clone			O(1)		O(1)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+//	Insert/Delete		O(logN)		O(logN)
+func PersistentTreeMapKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	// The nodes are not shared yet, so the tree can be built in place.
+	data := newTree[Key, Value](cmp.Compare[Key])
+	for i := range values {
+		data.Upsert(selector(values[i]), values[i])
+	}
+	return &orderedKeyValueSet[Key, Value, *persistentTreeMapStore[Key, Value]]{
+		keyValueSet: &keyValueSet[Key, Value, *persistentTreeMapStore[Key, Value]]{
+			store: &persistentTreeMapStore[Key, Value]{
+				store: data,
+			},
+			selector: selector,
+		},
+	}
+}
+
+// PersistentTreeMapKey is a thread-safe persistent AVL tree key set implementation.
+// Clone is O(1), and derived sets such as Union and Difference share all unmodified nodes with the receiver,
+// so keeping many versions of a large set only costs the keys that differ between them.
+// Iteration traverses an immutable snapshot, taken when the iteration starts.
+// Update modifies a private version of the set, published if fn succeeds, so readers are never blocked nor see it half-applied.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Clone			O(1)		O(1)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+//	Insert/Delete		O(logN)		O(logN)
+func PersistentTreeMapKey[Key constraints.Ordered](keys ...Key) OrderedKeySet[Key] {
+	// The nodes are not shared yet, so the tree can be built in place.
+	data := newTree[Key, empty](cmp.Compare[Key])
+	for _, key := range keys {
+		data.Upsert(key, empty{})
+	}
+	return &orderedKeySet[Key, *persistentTreeMapStore[Key, empty]]{
+		keySet: &keySet[Key, *persistentTreeMapStore[Key, empty]]{
+			store: &persistentTreeMapStore[Key, empty]{
+				store: data,
+			},
+		},
+	}
+}
+
+// snapshot returns the current version of the tree, which is never modified afterwards.
+func (t *persistentTreeMapStore[Key, Value]) snapshot() *tree[Key, Value] {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.share()
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Clear() {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.store.Clear()
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Clone() Storage[Key, Value] {
+	return &persistentTreeMapStore[Key, Value]{
+		store: t.snapshot(),
+	}
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Contains(key Key) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.Contains(key)
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Delete(keys ...Key) {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, key := range keys {
+		t.store.persistentDelete(key)
+	}
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Get(key Key) (Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.Get(key)
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for key, value := range t.snapshot().Ascend(nil, nil) {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Len() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.Len()
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Upsert(key Key, value Value) {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.store.persistentUpsert(key, value)
+}
+
+func (t *persistentTreeMapStore[Key, Value]) compute(key Key, fn func(old Value, found bool) (Value, computeOp)) (Value, bool) {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	old, found := t.store.Get(key)
+	return applyCompute(old, found, fn,
+		func(value Value) { t.store.persistentUpsert(key, value) },
+		func() { t.store.persistentDelete(key) },
+	)
+}
+
+func (t *persistentTreeMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		inserted[i] = t.store.persistentUpsert(key, valueAt(values, i))
+	}
+	return inserted
+}
+
+func (t *persistentTreeMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		deleted[i] = t.store.persistentDelete(key)
+	}
+	return deleted
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Min() (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Min())
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Max() (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Max())
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Floor(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Floor(key))
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Ceiling(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Ceiling(key))
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Lower(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Lower(key))
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Higher(key Key) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.Higher(key))
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Rank(key Key) int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.store.Rank(key)
+}
+
+func (t *persistentTreeMapStore[Key, Value]) At(i int) (Key, Value, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return nodeEntry(t.store.At(i))
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Range(from, to Key) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for key, value := range t.snapshot().Ascend(&from, &to) {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+func (t *persistentTreeMapStore[Key, Value]) Backward() iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		for key, value := range t.snapshot().Descend(nil) {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+// locked runs fn against a private version of the tree, published when fn returns,
+// so readers keep seeing the previous version in the meantime, while other writers wait for it.
+func (t *persistentTreeMapStore[Key, Value]) locked(fn func(view Storage[Key, Value])) {
+	t.writer.Lock()
+	defer t.writer.Unlock()
+
+	view := &persistentTreeMapStore[Key, Value]{store: t.snapshot()}
+	fn(storeView[Key, Value]{Storage: view, BatchStorage: view})
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.store = view.store
+}
+
+// persistentTreeMapStore is not a sortedStorage on purpose: bulk loading the result of a merge would copy every node,
+// while the generic algorithms clone in O(1) and only copy the paths of modified keys.
+var (
+	_ orderedStorage[string, string]   = &persistentTreeMapStore[string, string]{}
+	_ computingStorage[string, string] = &persistentTreeMapStore[string, string]{}
+	_ lockingStorage[string, string]   = &persistentTreeMapStore[string, string]{}
+)
@@ -1,61 +1,106 @@
 package kset
 
 import (
+	"cmp"
 	"iter"
 
-	"github.com/igrmk/treemap/v2"
 	"golang.org/x/exp/constraints"
 )
 
-type unsafeTreeMapStore[Key constraints.Ordered, Value any] struct {
-	store *treemap.TreeMap[Key, Value]
+type unsafeTreeMapStore[Key, Value any] struct {
+	store *tree[Key, Value]
 }
 
-// UnsafeTreeMapKeyValue is a thread-unsafe red-black tree key-value set implementation.
+// UnsafeTreeMapKeyValue is a thread-unsafe AVL tree key-value set implementation.
+// The tree is augmented with subtree sizes, providing order statistics through Rank and At.
 //
 //	Operation		Average		WorstCase
 //	Search			O(logN)		O(logN)
 //	Insert			O(logN)		O(logN)
 //	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
 //
 // Space complexity
 //
 //	Space			O(n)		O(n)
-func UnsafeTreeMapKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) KeyValueSet[Key, Value] {
-	data := treemap.New[Key, Value]()
+func UnsafeTreeMapKeyValue[Key constraints.Ordered, Value any](selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	return UnsafeTreeMapKeyValueFunc(cmp.Compare[Key], selector, values...)
+}
+
+// UnsafeTreeMapKeyValueFunc is a thread-unsafe AVL tree key-value set implementation, sorted by the given comparison function.
+// compare must return a negative number when a < b, a positive number when a > b and zero when a == b,
+// following the conventions of cmp.Compare and slices.SortFunc.
+// Keys are considered equal when compare returns zero.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func UnsafeTreeMapKeyValueFunc[Key comparable, Value any](compare func(a, b Key) int, selector func(Value) Key, values ...Value) OrderedKeyValueSet[Key, Value] {
+	data := newTree[Key, Value](compare)
 
 	for i := range values {
-		data.Set(selector(values[i]), values[i])
+		data.Upsert(selector(values[i]), values[i])
 	}
 
-	return &keyValueSet[Key, Value, *unsafeTreeMapStore[Key, Value]]{
-		store: &unsafeTreeMapStore[Key, Value]{
-			store: data,
+	return &orderedKeyValueSet[Key, Value, *unsafeTreeMapStore[Key, Value]]{
+		keyValueSet: &keyValueSet[Key, Value, *unsafeTreeMapStore[Key, Value]]{
+			store: &unsafeTreeMapStore[Key, Value]{
+				store: data,
+			},
+			selector: selector,
 		},
-		selector: selector,
 	}
 }
 
-// UnsafeTreeMapKey is a thread-unsafe red-black tree key set implementation.
+// UnsafeTreeMapKey is a thread-unsafe AVL tree key set implementation.
+// The tree is augmented with subtree sizes, providing order statistics through Rank and At.
+//
+//	Operation		Average		WorstCase
+//	Search			O(logN)		O(logN)
+//	Insert			O(logN)		O(logN)
+//	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
+//
+// Space complexity
+//
+//	Space			O(n)		O(n)
+func UnsafeTreeMapKey[Key constraints.Ordered](keys ...Key) OrderedKeySet[Key] {
+	return UnsafeTreeMapKeyFunc(cmp.Compare[Key], keys...)
+}
+
+// UnsafeTreeMapKeyFunc is a thread-unsafe AVL tree key set implementation, sorted by the given comparison function.
+// It allows keys that are not ordered by the language, such as structs and time.Time, to be kept sorted.
+// compare must return a negative number when a < b, a positive number when a > b and zero when a == b,
+// following the conventions of cmp.Compare and slices.SortFunc.
+// Keys are considered equal when compare returns zero.
 //
 //	Operation		Average		WorstCase
 //	Search			O(logN)		O(logN)
 //	Insert			O(logN)		O(logN)
 //	Delete			O(logN)		O(logN)
+//	Rank/At			O(logN)		O(logN)
 //
 // Space complexity
 //
 //	Space			O(n)		O(n)
-func UnsafeTreeMapKey[Key constraints.Ordered](keys ...Key) KeySet[Key] {
-	data := treemap.New[Key, empty]()
+func UnsafeTreeMapKeyFunc[Key any](compare func(a, b Key) int, keys ...Key) OrderedKeySet[Key] {
+	data := newTree[Key, empty](compare)
 
 	for _, key := range keys {
-		data.Set(key, empty{})
+		data.Upsert(key, empty{})
 	}
 
-	return &keySet[Key, *unsafeTreeMapStore[Key, empty]]{
-		store: &unsafeTreeMapStore[Key, empty]{
-			store: data,
+	return &orderedKeySet[Key, *unsafeTreeMapStore[Key, empty]]{
+		keySet: &keySet[Key, *unsafeTreeMapStore[Key, empty]]{
+			store: &unsafeTreeMapStore[Key, empty]{
+				store: data,
+			},
 		},
 	}
 }
@@ -65,14 +110,8 @@ func (t *unsafeTreeMapStore[Key, Value]) Clear() {
 }
 
 func (t *unsafeTreeMapStore[Key, Value]) Clone() Storage[Key, Value] {
-	clone := treemap.New[Key, Value]()
-
-	for iter := t.store.Iterator(); iter.Valid(); iter.Next() {
-		clone.Set(iter.Key(), iter.Value())
-	}
-
 	return &unsafeTreeMapStore[Key, Value]{
-		store: clone,
+		store: t.store.Clone(),
 	}
 }
 
@@ -82,7 +121,7 @@ func (t *unsafeTreeMapStore[Key, Value]) Contains(key Key) bool {
 
 func (t *unsafeTreeMapStore[Key, Value]) Delete(keys ...Key) {
 	for _, key := range keys {
-		t.store.Del(key)
+		t.store.Delete(key)
 	}
 }
 
@@ -91,13 +130,7 @@ func (t *unsafeTreeMapStore[Key, Value]) Get(key Key) (Value, bool) {
 }
 
 func (t *unsafeTreeMapStore[Key, Value]) Iter() iter.Seq2[Key, Value] {
-	return func(yield func(Key, Value) bool) {
-		for iter := t.store.Iterator(); iter.Valid(); iter.Next() {
-			if !yield(iter.Key(), iter.Value()) {
-				return
-			}
-		}
-	}
+	return t.store.Ascend(nil, nil)
 }
 
 func (t *unsafeTreeMapStore[Key, Value]) Len() int {
@@ -105,7 +138,65 @@ func (t *unsafeTreeMapStore[Key, Value]) Len() int {
 }
 
 func (t *unsafeTreeMapStore[Key, Value]) Upsert(key Key, value Value) {
-	t.store.Set(key, value)
+	t.store.Upsert(key, value)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	return t.store.UpsertMany(keys, values)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) DeleteMany(keys []Key) []bool {
+	return t.store.DeleteMany(keys)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Min() (Key, Value, bool) {
+	return nodeEntry(t.store.Min())
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Max() (Key, Value, bool) {
+	return nodeEntry(t.store.Max())
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Floor(key Key) (Key, Value, bool) {
+	return nodeEntry(t.store.Floor(key))
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Ceiling(key Key) (Key, Value, bool) {
+	return nodeEntry(t.store.Ceiling(key))
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Lower(key Key) (Key, Value, bool) {
+	return nodeEntry(t.store.Lower(key))
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Higher(key Key) (Key, Value, bool) {
+	return nodeEntry(t.store.Higher(key))
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Rank(key Key) int {
+	return t.store.Rank(key)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) At(i int) (Key, Value, bool) {
+	return nodeEntry(t.store.At(i))
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Range(from, to Key) iter.Seq2[Key, Value] {
+	return t.store.Ascend(&from, &to)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) Backward() iter.Seq2[Key, Value] {
+	return t.store.Descend(nil)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) compare(a, b Key) int {
+	return t.store.cmp(a, b)
+}
+
+func (t *unsafeTreeMapStore[Key, Value]) loadSorted(keys []Key, values []Value) Storage[Key, Value] {
+	return &unsafeTreeMapStore[Key, Value]{
+		store: t.store.loadSorted(keys, values),
+	}
 }
 
-var _ Storage[string, string] = &unsafeTreeMapStore[string, string]{}
+var _ sortedStorage[string, string] = &unsafeTreeMapStore[string, string]{}
@@ -0,0 +1,201 @@
+package kset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"slices"
+)
+
+const (
+	// streamChunkSize is the number of elements encoded in each chunk of a stream.
+	streamChunkSize = 4096
+	// maxStreamChunk bounds the size of the chunks accepted when reading a stream, protecting against corrupted lengths.
+	maxStreamChunk = 1 << 30
+)
+
+// streamChecksum is the table of the CRC-32 checksum following each chunk of a stream.
+var streamChecksum = crc32.MakeTable(crc32.Castagnoli)
+
+// The stream format is the binary format version, followed by chunks, followed by an empty chunk marking its end.
+// Each chunk is its length as an uvarint, the encoding of up to streamChunkSize elements as in MarshalBinary,
+// and the CRC-32C checksum of the encoding in little endian.
+
+// WriteToWith streams the values of the set to w, encoding each one with the given codec.
+// The stream can be read with ReadFromWith and the same codec. Like WriteTo, it holds the read lock of thread-safe sets while writing.
+// Example:
+//
+//	n, err := kset.WriteToWith(users, file, kset.JSONCodec[User]())
+func WriteToWith[Key comparable, Value any](set ReadOnlyKeyValueSet[Key, Value], w io.Writer, codec Codec[Value]) (int64, error) {
+	return writeStream(w, valuesOf(entriesIn(set)), codec)
+}
+
+// ReadFromWith replaces the elements of the set with values streamed by WriteToWith, decoding each one with the given codec.
+// Keys are derived from the decoded values through the selector of the set. Like ReadFrom, it follows the decoding rules of Set.
+// Example:
+//
+//	users := kset.HashMapKeyValue(func(u User) int { return u.ID })
+//	n, err := kset.ReadFromWith(users, file, kset.JSONCodec[User]())
+func ReadFromWith[Key comparable, Value any](set KeyValueSet[Key, Value], r io.Reader, codec Codec[Value]) (int64, error) {
+	var n int64
+	err := replaceValues(set, func(add func([]Value) error) (err error) {
+		n, err = readStream(r, codec, add)
+		return err
+	})
+	return n, err
+}
+
+func (k *keySet[Key, Store]) WriteTo(w io.Writer) (int64, error) {
+	return writeStream[Key](w, keysOf(k.store.Iter()), nil)
+}
+
+func (k *keySet[Key, Store]) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	err := replaceKeys(k, func(add func([]Key) error) (err error) {
+		n, err = readStream(r, nil, add)
+		return err
+	})
+	return n, err
+}
+
+func (k *keyValueSet[Key, Value, Store]) WriteTo(w io.Writer) (int64, error) {
+	return WriteToWith(k, w, nil)
+}
+
+func (k *keyValueSet[Key, Value, Store]) ReadFrom(r io.Reader) (int64, error) {
+	return ReadFromWith(k, r, nil)
+}
+
+// writeStream writes the elements to w in chunks, returning the number of bytes written.
+func writeStream[T any](w io.Writer, elements iter.Seq[T], codec Codec[T]) (int64, error) {
+	written, err := w.Write([]byte{binaryVersion})
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	chunk := make([]T, 0, streamChunkSize)
+	var payload, frame []byte
+
+	flush := func() error {
+		var err error
+		if payload, err = appendElements(payload[:0], chunk, codec); err != nil {
+			return err
+		}
+		chunk = chunk[:0]
+
+		frame = binary.AppendUvarint(frame[:0], uint64(len(payload)))
+		frame = append(frame, payload...)
+		frame = binary.LittleEndian.AppendUint32(frame, crc32.Checksum(payload, streamChecksum))
+
+		written, err := w.Write(frame)
+		total += int64(written)
+		return err
+	}
+
+	for element := range elements {
+		chunk = append(chunk, element)
+		if len(chunk) == streamChunkSize {
+			if err = flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		if err = flush(); err != nil {
+			return total, err
+		}
+	}
+
+	written, err = w.Write([]byte{0})
+	return total + int64(written), err
+}
+
+// readStream reads a stream written by writeStream, passing the elements of each chunk to load, stopping at its first error.
+// The chunk slice is reused, so load must not retain it.
+// It reads exactly up to the end of the stream, returning the number of bytes read.
+func readStream[T any](r io.Reader, codec Codec[T], load func([]T) error) (int64, error) {
+	reader := &streamReader{r: r}
+
+	version, err := reader.ReadByte()
+	if err != nil {
+		return reader.n, streamError(err)
+	}
+	if version != binaryVersion {
+		return reader.n, fmt.Errorf("%w: unsupported version %d", ErrInvalidBinary, version)
+	}
+
+	chunk := make([]T, 0, streamChunkSize)
+	var payload []byte
+	var checksum [4]byte
+
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return reader.n, streamError(err)
+		}
+		if size == 0 {
+			return reader.n, nil
+		}
+		if size > maxStreamChunk {
+			return reader.n, fmt.Errorf("%w: chunk of %d bytes", ErrInvalidBinary, size)
+		}
+
+		payload = slices.Grow(payload[:0], int(size))[:size]
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return reader.n, streamError(err)
+		}
+		if _, err := io.ReadFull(reader, checksum[:]); err != nil {
+			return reader.n, streamError(err)
+		}
+		if crc32.Checksum(payload, streamChecksum) != binary.LittleEndian.Uint32(checksum[:]) {
+			return reader.n, fmt.Errorf("%w: checksum mismatch", ErrInvalidBinary)
+		}
+
+		chunk = chunk[:0]
+		rest, err := decodeElements(payload, codec, func(int) {}, func(element T) {
+			chunk = append(chunk, element)
+		})
+		if err != nil {
+			return reader.n, err
+		}
+		if len(rest) > 0 {
+			return reader.n, fmt.Errorf("%w: %d trailing bytes in chunk", ErrInvalidBinary, len(rest))
+		}
+		if err := load(chunk); err != nil {
+			return reader.n, err
+		}
+	}
+}
+
+// streamError reports streams ending before their end marker as invalid.
+func streamError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", ErrInvalidBinary, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// streamReader counts the bytes read, and reads single bytes without buffering,
+// so nothing is consumed from the underlying reader past the end of the stream.
+type streamReader struct {
+	r   io.Reader
+	n   int64
+	buf [1]byte
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += int64(n)
+	return n, err
+}
+
+func (s *streamReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(s, s.buf[:]); err != nil {
+		return 0, err
+	}
+	return s.buf[0], nil
+}
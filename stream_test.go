@@ -0,0 +1,169 @@
+package kset_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeySet_Stream(t *testing.T) {
+	// Enough keys to span several chunks.
+	keys := make([]int, 10_000)
+	for i := range keys {
+		keys[i] = i*7 - 5000
+	}
+
+	forEachStoreK(t, func(t *testing.T, constructor func(values ...int) kset.KeySet[int]) {
+		t.Run("round trip", func(t *testing.T) {
+			var buffer bytes.Buffer
+			written, err := constructor(keys...).WriteTo(&buffer)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buffer.Len()), written)
+
+			decoded := constructor(1, 2)
+			read, err := decoded.ReadFrom(iotest.OneByteReader(&buffer))
+			require.NoError(t, err)
+			assert.Equal(t, written, read)
+			assert.True(t, decoded.Equal(kset.HashMapKey(keys...)))
+			assert.Equal(t, len(keys), decoded.Len())
+		})
+
+		t.Run("empty", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor().WriteTo(&buffer)
+			require.NoError(t, err)
+
+			decoded := constructor(1)
+			_, err = decoded.ReadFrom(&buffer)
+			require.NoError(t, err)
+			assert.True(t, decoded.IsEmpty())
+		})
+
+		t.Run("reads up to the end", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor(1, 2).WriteTo(&buffer)
+			require.NoError(t, err)
+			_, err = constructor(3).WriteTo(&buffer)
+			require.NoError(t, err)
+
+			first, second := constructor(), constructor()
+			_, err = first.ReadFrom(&buffer)
+			require.NoError(t, err)
+			_, err = second.ReadFrom(&buffer)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, []int{1, 2}, first.Slice())
+			assert.ElementsMatch(t, []int{3}, second.Slice())
+		})
+
+		t.Run("corrupted", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor(keys...).WriteTo(&buffer)
+			require.NoError(t, err)
+
+			data := buffer.Bytes()
+			data[len(data)/2] ^= 1
+
+			set := constructor(1)
+			_, err = set.ReadFrom(bytes.NewReader(data))
+			require.ErrorIs(t, err, kset.ErrInvalidBinary)
+			assert.Equal(t, []int{1}, set.Slice())
+		})
+
+		t.Run("truncated", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor(keys...).WriteTo(&buffer)
+			require.NoError(t, err)
+
+			data := buffer.Bytes()
+			for _, size := range []int{0, 1, 2, len(data) / 2, len(data) - 1} {
+				_, err = constructor().ReadFrom(bytes.NewReader(data[:size]))
+				require.ErrorIs(t, err, io.ErrUnexpectedEOF, "truncated to %d bytes", size)
+				require.ErrorIs(t, err, kset.ErrInvalidBinary)
+			}
+		})
+
+		t.Run("write error", func(t *testing.T) {
+			errWrite := errors.New("write error")
+			_, err := constructor(keys...).WriteTo(failingWriter{errWrite})
+			require.ErrorIs(t, err, errWrite)
+		})
+	})
+
+	t.Run("keys rejected by the storage", func(t *testing.T) {
+		var buffer bytes.Buffer
+		_, err := kset.HashMapKey(4, -1).WriteTo(&buffer)
+		require.NoError(t, err)
+
+		set := kset.BitSetKey(1, 2, 3)
+		_, err = set.ReadFrom(&buffer)
+		require.ErrorIs(t, err, kset.ErrInvalidKey)
+		assert.Equal(t, []int{1, 2, 3}, set.Slice())
+	})
+}
+
+func Test_KeyValueSet_Stream(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	userID := func(u user) int { return u.ID }
+
+	users := make([]user, 5000)
+	expected := make(map[int]user, len(users))
+	for i := range users {
+		users[i] = user{ID: i, Name: "user"}
+		expected[i] = users[i]
+	}
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.KeyValueSet[int, user]) {
+		t.Run("round trip", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor(userID, users...).WriteTo(&buffer)
+			require.NoError(t, err)
+
+			decoded := constructor(userID)
+			_, err = decoded.ReadFrom(&buffer)
+			require.NoError(t, err)
+			assert.Equal(t, expected, decoded.Map())
+		})
+
+		t.Run("codec", func(t *testing.T) {
+			codec := kset.JSONCodec[user]()
+
+			var buffer bytes.Buffer
+			_, err := kset.WriteToWith(constructor(userID, users...), &buffer, codec)
+			require.NoError(t, err)
+
+			decoded := constructor(userID)
+			_, err = kset.ReadFromWith(decoded, &buffer, codec)
+			require.NoError(t, err)
+			assert.Equal(t, expected, decoded.Map())
+		})
+
+		t.Run("truncated", func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := constructor(userID, users...).WriteTo(&buffer)
+			require.NoError(t, err)
+
+			set := constructor(userID, user{ID: -1})
+			_, err = set.ReadFrom(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.Equal(t, map[int]user{-1: {ID: -1}}, set.Map())
+		})
+	})
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (f failingWriter) Write([]byte) (int, error) {
+	return 0, f.err
+}
@@ -0,0 +1,442 @@
+package kset
+
+import (
+	"iter"
+)
+
+// tree is a size-augmented AVL tree, sorted by cmp.
+// Every node tracks the size of its subtree, allowing order statistics in O(logN).
+type tree[Key, Value any] struct {
+	root *treeNode[Key, Value]
+	cmp  func(a, b Key) int
+}
+
+type treeNode[Key, Value any] struct {
+	key    Key
+	value  Value
+	left   *treeNode[Key, Value]
+	right  *treeNode[Key, Value]
+	height int
+	size   int
+}
+
+func newTree[Key, Value any](cmp func(a, b Key) int) *tree[Key, Value] {
+	return &tree[Key, Value]{
+		cmp: cmp,
+	}
+}
+
+func (n *treeNode[Key, Value]) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *treeNode[Key, Value]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// update recalculates the height and size of the node from its children.
+func (n *treeNode[Key, Value]) update() {
+	n.height = max(n.left.getHeight(), n.right.getHeight()) + 1
+	n.size = n.left.getSize() + n.right.getSize() + 1
+}
+
+func (n *treeNode[Key, Value]) rotateLeft() *treeNode[Key, Value] {
+	root := n.right
+	n.right = root.left
+	n.update()
+	root.left = n
+	root.update()
+	return root
+}
+
+func (n *treeNode[Key, Value]) rotateRight() *treeNode[Key, Value] {
+	root := n.left
+	n.left = root.right
+	n.update()
+	root.right = n
+	root.update()
+	return root
+}
+
+// balance restores the AVL property of the node, returning the new subtree root.
+func (n *treeNode[Key, Value]) balance() *treeNode[Key, Value] {
+	n.update()
+
+	switch factor := n.left.getHeight() - n.right.getHeight(); {
+	case factor > 1:
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case factor < -1:
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	default:
+		return n
+	}
+}
+
+func (t *tree[Key, Value]) Len() int {
+	return t.root.getSize()
+}
+
+func (t *tree[Key, Value]) Clear() {
+	t.root = nil
+}
+
+// Clone copies the structure of the tree in O(n), without re-balancing it.
+func (t *tree[Key, Value]) Clone() *tree[Key, Value] {
+	var clone func(n *treeNode[Key, Value]) *treeNode[Key, Value]
+	clone = func(n *treeNode[Key, Value]) *treeNode[Key, Value] {
+		if n == nil {
+			return nil
+		}
+		node := *n
+		node.left = clone(n.left)
+		node.right = clone(n.right)
+		return &node
+	}
+
+	return &tree[Key, Value]{
+		root: clone(t.root),
+		cmp:  t.cmp,
+	}
+}
+
+func (t *tree[Key, Value]) find(key Key) *treeNode[Key, Value] {
+	n := t.root
+	for n != nil {
+		switch c := t.cmp(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+func (t *tree[Key, Value]) Get(key Key) (Value, bool) {
+	if n := t.find(key); n != nil {
+		return n.value, true
+	}
+	var zero Value
+	return zero, false
+}
+
+func (t *tree[Key, Value]) Contains(key Key) bool {
+	return t.find(key) != nil
+}
+
+// Upsert inserts or replaces the value of the key. It returns true if the key is new.
+func (t *tree[Key, Value]) Upsert(key Key, value Value) bool {
+	var inserted bool
+	t.root = t.upsert(t.root, key, value, &inserted)
+	return inserted
+}
+
+func (t *tree[Key, Value]) upsert(n *treeNode[Key, Value], key Key, value Value, inserted *bool) *treeNode[Key, Value] {
+	if n == nil {
+		*inserted = true
+		return &treeNode[Key, Value]{key: key, value: value, height: 1, size: 1}
+	}
+
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		n.left = t.upsert(n.left, key, value, inserted)
+	case c > 0:
+		n.right = t.upsert(n.right, key, value, inserted)
+	default:
+		n.value = value
+		return n
+	}
+
+	if !*inserted {
+		return n
+	}
+	return n.balance()
+}
+
+// Delete removes the key from the tree. It returns true if the key was found.
+func (t *tree[Key, Value]) Delete(key Key) bool {
+	var deleted bool
+	t.root = t.delete(t.root, key, &deleted)
+	return deleted
+}
+
+// UpsertMany upserts the entries in order, returning whether each key was inserted.
+// values can be nil, in which case all values are zero.
+func (t *tree[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		inserted[i] = t.Upsert(key, valueAt(values, i))
+	}
+	return inserted
+}
+
+// DeleteMany deletes the keys in order, returning whether each key was found.
+func (t *tree[Key, Value]) DeleteMany(keys []Key) []bool {
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		deleted[i] = t.Delete(key)
+	}
+	return deleted
+}
+
+func (t *tree[Key, Value]) delete(n *treeNode[Key, Value], key Key, deleted *bool) *treeNode[Key, Value] {
+	if n == nil {
+		return nil
+	}
+
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		n.left = t.delete(n.left, key, deleted)
+	case c > 0:
+		n.right = t.delete(n.right, key, deleted)
+	default:
+		*deleted = true
+		if n.left == nil {
+			return n.right
+		}
+		if n.right == nil {
+			return n.left
+		}
+		var successor *treeNode[Key, Value]
+		n.right = deleteMin(n.right, &successor)
+		successor.left, successor.right = n.left, n.right
+		n = successor
+	}
+
+	if !*deleted {
+		return n
+	}
+	return n.balance()
+}
+
+// deleteMin detaches the smallest node of the subtree into min, returning the new subtree root.
+func deleteMin[Key, Value any](n *treeNode[Key, Value], min **treeNode[Key, Value]) *treeNode[Key, Value] {
+	if n.left == nil {
+		*min = n
+		return n.right
+	}
+	n.left = deleteMin(n.left, min)
+	return n.balance()
+}
+
+func (t *tree[Key, Value]) Min() *treeNode[Key, Value] {
+	n := t.root
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (t *tree[Key, Value]) Max() *treeNode[Key, Value] {
+	n := t.root
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// Floor returns the node with the greatest key less than or equal to the given key.
+func (t *tree[Key, Value]) Floor(key Key) *treeNode[Key, Value] {
+	var best *treeNode[Key, Value]
+	for n := t.root; n != nil; {
+		switch c := t.cmp(n.key, key); {
+		case c == 0:
+			return n
+		case c < 0:
+			best, n = n, n.right
+		default:
+			n = n.left
+		}
+	}
+	return best
+}
+
+// Ceiling returns the node with the smallest key greater than or equal to the given key.
+func (t *tree[Key, Value]) Ceiling(key Key) *treeNode[Key, Value] {
+	var best *treeNode[Key, Value]
+	for n := t.root; n != nil; {
+		switch c := t.cmp(n.key, key); {
+		case c == 0:
+			return n
+		case c > 0:
+			best, n = n, n.left
+		default:
+			n = n.right
+		}
+	}
+	return best
+}
+
+// Lower returns the node with the greatest key strictly less than the given key.
+func (t *tree[Key, Value]) Lower(key Key) *treeNode[Key, Value] {
+	var best *treeNode[Key, Value]
+	for n := t.root; n != nil; {
+		if t.cmp(n.key, key) < 0 {
+			best, n = n, n.right
+		} else {
+			n = n.left
+		}
+	}
+	return best
+}
+
+// Higher returns the node with the smallest key strictly greater than the given key.
+func (t *tree[Key, Value]) Higher(key Key) *treeNode[Key, Value] {
+	var best *treeNode[Key, Value]
+	for n := t.root; n != nil; {
+		if t.cmp(n.key, key) > 0 {
+			best, n = n, n.left
+		} else {
+			n = n.right
+		}
+	}
+	return best
+}
+
+// Rank returns the number of keys strictly less than the given key.
+func (t *tree[Key, Value]) Rank(key Key) int {
+	rank := 0
+	for n := t.root; n != nil; {
+		if t.cmp(key, n.key) <= 0 {
+			n = n.left
+		} else {
+			rank += n.left.getSize() + 1
+			n = n.right
+		}
+	}
+	return rank
+}
+
+// At returns the node at the given position of the ascending order, or nil if out of bounds.
+func (t *tree[Key, Value]) At(i int) *treeNode[Key, Value] {
+	if i < 0 || i >= t.Len() {
+		return nil
+	}
+	n := t.root
+	for {
+		switch leftSize := n.left.getSize(); {
+		case i < leftSize:
+			n = n.left
+		case i == leftSize:
+			return n
+		default:
+			i -= leftSize + 1
+			n = n.right
+		}
+	}
+}
+
+// Ascend iterates in ascending order through the keys in the interval [from, to).
+// A nil bound means the interval is unbounded on that side.
+func (t *tree[Key, Value]) Ascend(from, to *Key) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		stack := make([]*treeNode[Key, Value], 0, t.root.getHeight())
+
+		for n := t.root; n != nil; {
+			if from == nil || t.cmp(n.key, *from) >= 0 {
+				stack = append(stack, n)
+				n = n.left
+			} else {
+				n = n.right
+			}
+		}
+
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if to != nil && t.cmp(n.key, *to) >= 0 {
+				return
+			}
+			if !yield(n.key, n.value) {
+				return
+			}
+
+			for n = n.right; n != nil; n = n.left {
+				stack = append(stack, n)
+			}
+		}
+	}
+}
+
+// Descend iterates in descending order through the keys less than before, or through all keys if before is nil.
+func (t *tree[Key, Value]) Descend(before *Key) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		stack := make([]*treeNode[Key, Value], 0, t.root.getHeight())
+
+		for n := t.root; n != nil; {
+			if before == nil || t.cmp(n.key, *before) < 0 {
+				stack = append(stack, n)
+				n = n.right
+			} else {
+				n = n.left
+			}
+		}
+
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n.key, n.value) {
+				return
+			}
+
+			for n = n.left; n != nil; n = n.right {
+				stack = append(stack, n)
+			}
+		}
+	}
+}
+
+// nodeEntry returns the entry of the node, or false if the node is nil.
+func nodeEntry[Key, Value any](n *treeNode[Key, Value]) (Key, Value, bool) {
+	if n == nil {
+		var key Key
+		var value Value
+		return key, value, false
+	}
+	return n.key, n.value, true
+}
+
+// loadSorted creates a balanced tree from strictly ascending keys in O(n).
+// values can be nil, in which case all values are zero.
+func (t *tree[Key, Value]) loadSorted(keys []Key, values []Value) *tree[Key, Value] {
+	// Nodes are allocated in a single block, improving locality and reducing allocations.
+	nodes := make([]treeNode[Key, Value], len(keys))
+
+	var build func(lo, hi int) *treeNode[Key, Value]
+	build = func(lo, hi int) *treeNode[Key, Value] {
+		if lo >= hi {
+			return nil
+		}
+		mid := int(uint(lo+hi) >> 1)
+		n := &nodes[mid]
+		n.key = keys[mid]
+		if values != nil {
+			n.value = values[mid]
+		}
+		n.left = build(lo, mid)
+		n.right = build(mid+1, hi)
+		n.update()
+		return n
+	}
+
+	return &tree[Key, Value]{
+		root: build(0, len(keys)),
+		cmp:  t.cmp,
+	}
+}
@@ -0,0 +1,132 @@
+package kset
+
+// Persistent operations never modify existing nodes, copying the path from the root to the changed node instead.
+// Trees sharing nodes with the modified one, such as previous versions, are not affected.
+
+func (n *treeNode[Key, Value]) copy() *treeNode[Key, Value] {
+	node := *n
+	return &node
+}
+
+// balanceCopy restores the AVL property of a copied node like balance,
+// also copying the children moved by rotations, as they might be shared.
+func (n *treeNode[Key, Value]) balanceCopy() *treeNode[Key, Value] {
+	n.update()
+
+	switch factor := n.left.getHeight() - n.right.getHeight(); {
+	case factor > 1:
+		n.left = n.left.copy()
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left.right = n.left.right.copy()
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case factor < -1:
+		n.right = n.right.copy()
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right.left = n.right.left.copy()
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	default:
+		return n
+	}
+}
+
+// persistentUpsert inserts or replaces the value of the key, copying the modified path. It returns true if the key is new.
+func (t *tree[Key, Value]) persistentUpsert(key Key, value Value) bool {
+	var inserted bool
+	t.root = t.upsertCopy(t.root, key, value, &inserted)
+	return inserted
+}
+
+func (t *tree[Key, Value]) upsertCopy(n *treeNode[Key, Value], key Key, value Value, inserted *bool) *treeNode[Key, Value] {
+	if n == nil {
+		*inserted = true
+		return &treeNode[Key, Value]{key: key, value: value, height: 1, size: 1}
+	}
+
+	n = n.copy()
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		n.left = t.upsertCopy(n.left, key, value, inserted)
+	case c > 0:
+		n.right = t.upsertCopy(n.right, key, value, inserted)
+	default:
+		n.value = value
+		return n
+	}
+
+	if !*inserted {
+		return n
+	}
+	return n.balanceCopy()
+}
+
+// persistentDelete removes the key, copying the modified path. It returns true if the key was found.
+// Nothing is copied when the key is missing.
+func (t *tree[Key, Value]) persistentDelete(key Key) bool {
+	var deleted bool
+	t.root = t.deleteCopy(t.root, key, &deleted)
+	return deleted
+}
+
+func (t *tree[Key, Value]) deleteCopy(n *treeNode[Key, Value], key Key, deleted *bool) *treeNode[Key, Value] {
+	if n == nil {
+		return nil
+	}
+
+	switch c := t.cmp(key, n.key); {
+	case c < 0:
+		left := t.deleteCopy(n.left, key, deleted)
+		if !*deleted {
+			return n
+		}
+		n = n.copy()
+		n.left = left
+	case c > 0:
+		right := t.deleteCopy(n.right, key, deleted)
+		if !*deleted {
+			return n
+		}
+		n = n.copy()
+		n.right = right
+	default:
+		*deleted = true
+		if n.left == nil {
+			return n.right
+		}
+		if n.right == nil {
+			return n.left
+		}
+		var successor *treeNode[Key, Value]
+		right := deleteMinCopy(n.right, &successor)
+		successor = successor.copy()
+		successor.left, successor.right = n.left, right
+		n = successor
+	}
+
+	return n.balanceCopy()
+}
+
+// deleteMinCopy detaches the smallest node of the subtree into min, copying the modified path.
+// The detached node itself is not copied.
+func deleteMinCopy[Key, Value any](n *treeNode[Key, Value], min **treeNode[Key, Value]) *treeNode[Key, Value] {
+	if n.left == nil {
+		*min = n
+		return n.right
+	}
+	left := deleteMinCopy(n.left, min)
+	n = n.copy()
+	n.left = left
+	return n.balanceCopy()
+}
+
+// share returns a tree sharing all nodes with this one in O(1).
+// Both trees must only be modified through persistent operations afterwards.
+func (t *tree[Key, Value]) share() *tree[Key, Value] {
+	return &tree[Key, Value]{
+		root: t.root,
+		cmp:  t.cmp,
+	}
+}
@@ -0,0 +1,245 @@
+package kset
+
+import "iter"
+
+// KeyTxn is the view of a key set inside a transaction started by Update.
+// Changes made through it are visible to its own reads, and are discarded if the transaction fails.
+type KeyTxn[Key any] interface {
+	// Len returns the number of keys in the set, including the changes of the transaction.
+	Len() int
+	// ContainsKeys checks if all the given keys are present in the set.
+	ContainsKeys(keys ...Key) bool
+	// Keys iterates through the keys of the set. The set must not be modified during the iteration.
+	Keys() iter.Seq[Key]
+	// Append adds keys to the set, returning the number of keys added.
+	Append(keys ...Key) int
+	// RemoveKeys removes the given keys from the set.
+	RemoveKeys(keys ...Key)
+	// Clear removes all keys from the set.
+	Clear()
+}
+
+// Txn is the view of a key-value set inside a transaction started by Update.
+// Changes made through it are visible to its own reads, and are discarded if the transaction fails.
+type Txn[Key comparable, Value any] interface {
+	// Len returns the number of elements in the set, including the changes of the transaction.
+	Len() int
+	// ContainsKeys checks if all the given keys are present in the set.
+	ContainsKeys(keys ...Key) bool
+	// Get returns the value stored for the key, and whether it was found.
+	Get(key Key) (Value, bool)
+	// KeyValues iterates through the elements of the set. The set must not be modified during the iteration.
+	KeyValues() iter.Seq2[Key, Value]
+	// Append upserts values to the set, returning the number of elements added.
+	Append(values ...Value) int
+	// Remove removes the given elements from the set.
+	Remove(values ...Value)
+	// RemoveKeys removes the given keys from the set.
+	RemoveKeys(keys ...Key)
+	// Clear removes all elements from the set.
+	Clear()
+}
+
+// keyTxn exposes only the KeyTxn methods of the set a transaction runs against,
+// so tx cannot be asserted into a KeySet to reach the methods of the throwaway set.
+type keyTxn[Key any] struct {
+	KeyTxn[Key]
+}
+
+// txn exposes only the Txn methods of the set a transaction runs against.
+type txn[Key comparable, Value any] struct {
+	Txn[Key, Value]
+}
+
+// txn returns the view of the set inside a transaction, applying its changes to the journal.
+func (k *keySet[Key, Store]) txn(j *journal[Key, empty]) KeyTxn[Key] {
+	return keyTxn[Key]{&keySet[Key, *journal[Key, empty]]{store: j}}
+}
+
+// txn returns the view of the set inside a transaction, applying its changes to the journal.
+func (k *keyValueSet[Key, Value, Store]) txn(j *journal[Key, Value]) Txn[Key, Value] {
+	return txn[Key, Value]{&keyValueSet[Key, Value, *journal[Key, Value]]{store: j, selector: k.selector}}
+}
+
+// Update runs fn in a transaction, returning its error.
+func (k *keySet[Key, Store]) Update(fn func(tx KeyTxn[Key]) error) error {
+	if k.observers.active() {
+		return k.updateObserved(fn)
+	}
+	return update[Key, empty](k.store, func(j *journal[Key, empty]) error {
+		return fn(k.txn(j))
+	})
+}
+
+// Update runs fn in a transaction, returning its error.
+func (k *keyValueSet[Key, Value, Store]) Update(fn func(tx Txn[Key, Value]) error) error {
+	if k.observers.active() {
+		return k.updateObserved(fn)
+	}
+	return update[Key, Value](k.store, func(j *journal[Key, Value]) error {
+		return fn(k.txn(j))
+	})
+}
+
+// update runs fn against a journal of the storage, holding its write lock if it is a lockingStorage.
+// The changes recorded by the journal are rolled back if fn returns an error or panics.
+func update[Key, Value any](store Storage[Key, Value], fn func(*journal[Key, Value]) error) error {
+	var err error
+	run := func(view Storage[Key, Value]) {
+		j := &journal[Key, Value]{Storage: view}
+		committed := false
+		defer func() {
+			if !committed {
+				j.rollback()
+			}
+		}()
+
+		if err = fn(j); err == nil {
+			committed = true
+		}
+	}
+
+	if locking, ok := store.(lockingStorage[Key, Value]); ok {
+		locking.locked(run)
+	} else {
+		run(store)
+	}
+	return err
+}
+
+// replaceKeys replaces the keys of the set with the ones passed to add by load, in a single transaction.
+// Keys the storage of the set cannot hold make add return ErrInvalidKey, and the set is not modified if load returns an error.
+func replaceKeys[Key any](set KeySet[Key], load func(add func(keys []Key) error) error) error {
+	return set.Update(func(tx KeyTxn[Key]) error {
+		tx.Clear()
+		return load(func(keys []Key) error {
+			if validator, ok := set.(keyValidator[Key]); ok {
+				if err := validator.validateKeys(keys); err != nil {
+					return err
+				}
+			}
+			tx.Append(keys...)
+			return nil
+		})
+	})
+}
+
+// replaceValues replaces the values of the set with the ones passed to add by load, in a single transaction.
+// The set is not modified if load returns an error.
+func replaceValues[Key comparable, Value any](set KeyValueSet[Key, Value], load func(add func(values []Value) error) error) error {
+	return set.Update(func(tx Txn[Key, Value]) error {
+		tx.Clear()
+		return load(func(values []Value) error {
+			tx.Append(values...)
+			return nil
+		})
+	})
+}
+
+// journal applies changes directly to a storage, recording the previous state of every key changed,
+// so the changes can be undone in reverse order.
+// Once cleared, it stops recording: its rollback clears the storage and restores the elements recorded by Clear instead.
+// When observed, it also records the events of the changes, applying batches one key at a time to report each of them.
+type journal[Key, Value any] struct {
+	Storage[Key, Value]
+	undo     []journalEntry[Key, Value]
+	cleared  bool
+	observed bool
+	events   []Event[Key, Value]
+}
+
+// journalEntry is the state of a key before it was changed.
+type journalEntry[Key, Value any] struct {
+	key     Key
+	value   Value
+	existed bool
+}
+
+func (j *journal[Key, Value]) record(key Key) (Value, bool) {
+	value, existed := j.Storage.Get(key)
+	if !j.cleared {
+		j.undo = append(j.undo, journalEntry[Key, Value]{key: key, value: value, existed: existed})
+	}
+	return value, existed
+}
+
+func (j *journal[Key, Value]) rollback() {
+	if j.cleared {
+		j.Storage.Clear()
+	}
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		entry := j.undo[i]
+		if entry.existed {
+			j.Storage.Upsert(entry.key, entry.value)
+		} else {
+			j.Storage.Delete(entry.key)
+		}
+	}
+	j.undo = nil
+	j.cleared = false
+	j.events = nil
+}
+
+func (j *journal[Key, Value]) Clear() {
+	if !j.cleared {
+		for key, value := range j.Storage.Iter() {
+			j.undo = append(j.undo, journalEntry[Key, Value]{key: key, value: value, existed: true})
+		}
+		j.cleared = true
+	}
+	j.Storage.Clear()
+	if j.observed {
+		j.events = append(j.events, Event[Key, Value]{Kind: EventClear})
+	}
+}
+
+func (j *journal[Key, Value]) Delete(keys ...Key) {
+	j.DeleteMany(keys)
+}
+
+func (j *journal[Key, Value]) DeleteMany(keys []Key) []bool {
+	if !j.observed {
+		for i := 0; i < len(keys) && !j.cleared; i++ {
+			j.record(keys[i])
+		}
+		return deleteMany(j.Storage, keys)
+	}
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		old, existed := j.record(key)
+		if existed {
+			j.Storage.Delete(key)
+			j.events = append(j.events, Event[Key, Value]{Kind: EventDelete, Key: key, Old: old})
+		}
+		deleted[i] = existed
+	}
+	return deleted
+}
+
+func (j *journal[Key, Value]) Upsert(key Key, value Value) {
+	j.UpsertMany([]Key{key}, []Value{value})
+}
+
+func (j *journal[Key, Value]) UpsertMany(keys []Key, values []Value) []bool {
+	if !j.observed {
+		for i := 0; i < len(keys) && !j.cleared; i++ {
+			j.record(keys[i])
+		}
+		return upsertMany(j.Storage, keys, values)
+	}
+
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		value := valueAt(values, i)
+		old, existed := j.record(key)
+		j.Storage.Upsert(key, value)
+		if existed {
+			j.events = append(j.events, Event[Key, Value]{Kind: EventReplace, Key: key, Value: value, Old: old})
+		} else {
+			j.events = append(j.events, Event[Key, Value]{Kind: EventInsert, Key: key, Value: value})
+		}
+		inserted[i] = !existed
+	}
+	return inserted
+}
@@ -0,0 +1,166 @@
+package kset_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sonalys/kset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeySet_Update(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	forEachStoreK(t, func(t *testing.T, constructor func(keys ...int) kset.KeySet[int]) {
+		t.Run("commit", func(t *testing.T) {
+			set := constructor(1, 4)
+			err := set.Update(func(tx kset.KeyTxn[int]) error {
+				_, isSet := tx.(kset.KeySet[int])
+				assert.False(t, isSet, "tx must not expose the set of the transaction")
+				if tx.ContainsKeys(1) {
+					tx.RemoveKeys(1)
+					assert.Equal(t, 2, tx.Append(2, 3))
+				}
+				assert.Equal(t, 3, tx.Len())
+				assert.ElementsMatch(t, []int{2, 3, 4}, slices.Collect(tx.Keys()))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []int{2, 3, 4}, set.Slice())
+		})
+
+		t.Run("error", func(t *testing.T) {
+			set := constructor(1, 4)
+			err := set.Update(func(tx kset.KeyTxn[int]) error {
+				tx.RemoveKeys(1, 4, 5)
+				tx.Append(2, 3, 2)
+				tx.RemoveKeys(2)
+				return errAbort
+			})
+			require.ErrorIs(t, err, errAbort)
+			assert.ElementsMatch(t, []int{1, 4}, set.Slice())
+		})
+
+		t.Run("clear", func(t *testing.T) {
+			set := constructor(1, 4)
+			err := set.Update(func(tx kset.KeyTxn[int]) error {
+				tx.RemoveKeys(4)
+				tx.Append(2)
+				tx.Clear()
+				assert.Equal(t, 0, tx.Len())
+				tx.Append(1, 3)
+				tx.Clear()
+				tx.Append(5)
+				return errAbort
+			})
+			require.ErrorIs(t, err, errAbort)
+			assert.ElementsMatch(t, []int{1, 4}, set.Slice())
+		})
+
+		t.Run("panic", func(t *testing.T) {
+			set := constructor(1, 4)
+			assert.PanicsWithValue(t, "abort", func() {
+				_ = set.Update(func(tx kset.KeyTxn[int]) error {
+					tx.RemoveKeys(1)
+					tx.Append(2)
+					panic("abort")
+				})
+			})
+			assert.ElementsMatch(t, []int{1, 4}, set.Slice())
+
+			// The set must remain usable after the panic.
+			set.Append(5)
+			assert.ElementsMatch(t, []int{1, 4, 5}, set.Slice())
+		})
+	})
+}
+
+func Test_KeyValueSet_Update(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	userID := func(u user) int { return u.ID }
+	errAbort := errors.New("abort")
+
+	forEachStore(t, func(t *testing.T, constructor func(selector func(user) int, values ...user) kset.KeyValueSet[int, user]) {
+		t.Run("commit", func(t *testing.T) {
+			set := constructor(userID, user{1, "a"}, user{2, "b"})
+			err := set.Update(func(tx kset.Txn[int, user]) error {
+				_, isSet := tx.(kset.KeyValueSet[int, user])
+				assert.False(t, isSet, "tx must not expose the set of the transaction")
+				current, ok := tx.Get(1)
+				require.True(t, ok)
+				tx.Append(user{1, current.Name + "!"}, user{3, "c"})
+				tx.Remove(user{2, "b"})
+
+				updated, _ := tx.Get(1)
+				assert.Equal(t, "a!", updated.Name)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, map[int]user{1: {1, "a!"}, 3: {3, "c"}}, set.Map())
+		})
+
+		t.Run("error", func(t *testing.T) {
+			set := constructor(userID, user{1, "a"}, user{2, "b"})
+			err := set.Update(func(tx kset.Txn[int, user]) error {
+				tx.Append(user{1, "x"}, user{3, "c"}, user{1, "y"})
+				tx.RemoveKeys(2, 3)
+				return errAbort
+			})
+			require.ErrorIs(t, err, errAbort)
+			assert.Equal(t, map[int]user{1: {1, "a"}, 2: {2, "b"}}, set.Map())
+		})
+	})
+}
+
+func Test_KeySet_Update_Concurrent(t *testing.T) {
+	stores := map[string]func(keys ...int) kset.KeySet[int]{
+		"HashMapKey":           kset.HashMapKey[int],
+		"TreeMapKey":           func(keys ...int) kset.KeySet[int] { return kset.TreeMapKey(keys...) },
+		"PersistentHashMapKey": kset.PersistentHashMapKey[int],
+		"PersistentTreeMapKey": func(keys ...int) kset.KeySet[int] { return kset.PersistentTreeMapKey(keys...) },
+		"SortedSliceKey":       func(keys ...int) kset.KeySet[int] { return kset.SortedSliceKey(keys...) },
+		"BitSetKey":            kset.BitSetKey[int],
+	}
+
+	for name, constructor := range stores {
+		t.Run(name, func(t *testing.T) {
+			testUpdateConcurrent(t, constructor(0))
+		})
+	}
+
+	t.Run("RoaringKey", func(t *testing.T) {
+		testUpdateConcurrent(t, kset.RoaringKey[uint32](0))
+	})
+}
+
+// testUpdateConcurrent increments the single counter held by the set from many goroutines.
+func testUpdateConcurrent[Key int | uint32](t *testing.T, set kset.KeySet[Key]) {
+	const workers, updates = 8, 200
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range updates {
+				err := set.Update(func(tx kset.KeyTxn[Key]) error {
+					counter := slices.Collect(tx.Keys())[0]
+					tx.RemoveKeys(counter)
+					tx.Append(counter + 1)
+					return nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, set.Len())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []Key{workers * updates}, set.Slice())
+}
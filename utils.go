@@ -1,6 +1,10 @@
 package kset
 
-import "iter"
+import (
+	"iter"
+	"slices"
+	"sync"
+)
 
 func bufferedCollect[T any](seq iter.Seq[T], size int) []T {
 	buffer := make([]T, 0, size)
@@ -9,3 +13,197 @@ func bufferedCollect[T any](seq iter.Seq[T], size int) []T {
 	}
 	return buffer
 }
+
+func keysOf[Key, Value any](seq iter.Seq2[Key, Value]) iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for key := range seq {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+func collectEntries[Key, Value any](seq iter.Seq2[Key, Value], size int) ([]Key, []Value) {
+	keys := make([]Key, 0, size)
+	values := make([]Value, 0, size)
+	for key, value := range seq {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+func valuesOf[Key, Value any](seq iter.Seq2[Key, Value]) iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for _, value := range seq {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// valueAt returns the i-th value of a batch, where nil values stand for zero values.
+func valueAt[Value any](values []Value, i int) Value {
+	if values == nil {
+		var zero Value
+		return zero
+	}
+	return values[i]
+}
+
+// upsertMany upserts the entries through the batch operation of the storage, or one by one if it has none.
+func upsertMany[Key, Value any](store Storage[Key, Value], keys []Key, values []Value) []bool {
+	if batch, ok := store.(BatchStorage[Key, Value]); ok {
+		return batch.UpsertMany(keys, values)
+	}
+
+	inserted := make([]bool, len(keys))
+	for i, key := range keys {
+		inserted[i] = !store.Contains(key)
+		store.Upsert(key, valueAt(values, i))
+	}
+	return inserted
+}
+
+// deleteMany deletes the keys through the batch operation of the storage, or one by one if it has none.
+func deleteMany[Key, Value any](store Storage[Key, Value], keys []Key) []bool {
+	if batch, ok := store.(BatchStorage[Key, Value]); ok {
+		return batch.DeleteMany(keys)
+	}
+
+	deleted := make([]bool, len(keys))
+	for i, key := range keys {
+		if deleted[i] = store.Contains(key); deleted[i] {
+			store.Delete(key)
+		}
+	}
+	return deleted
+}
+
+// countTrue returns the number of true outcomes of a batch operation.
+func countTrue(outcomes []bool) int {
+	count := 0
+	for _, outcome := range outcomes {
+		if outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// ascendingOrder returns the indexes of the keys sorted by them. Repeated keys keep their relative order.
+func ascendingOrder[Key any](keys []Key, compare func(a, b Key) int) []int {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return compare(keys[a], keys[b])
+	})
+	return order
+}
+
+// snapshotIter iterates through a snapshot of the storage if it is a snapshotStorage, and through Iter otherwise.
+func snapshotIter[Key, Value any](store Storage[Key, Value]) iter.Seq2[Key, Value] {
+	if snapshot, ok := store.(snapshotStorage[Key, Value]); ok {
+		return snapshot.iterSnapshot()
+	}
+	return store.Iter()
+}
+
+// keysIn iterates through the keys of the set, without copying it when it is a set of this package,
+// so algorithms stopping early do not pay for a snapshot. The loop body must not modify the set.
+func keysIn[Key any](set ReadOnlySet[Key]) iter.Seq[Key] {
+	if internal, ok := unfreeze(set).(interface{ storeKeys() iter.Seq[Key] }); ok {
+		return internal.storeKeys()
+	}
+	return set.Keys()
+}
+
+// entriesIn iterates through the entries of the set, without copying it when it is a set of this package.
+// The loop body must not modify the set.
+func entriesIn[Key comparable, Value any](set ReadOnlyKeyValueSet[Key, Value]) iter.Seq2[Key, Value] {
+	if internal, ok := unfreeze[Key](set).(interface{ storeEntries() iter.Seq2[Key, Value] }); ok {
+		return internal.storeEntries()
+	}
+	return set.KeyValues()
+}
+
+// lockedSnapshot collects the entries of seq while holding the read lock, and yields them once it is released.
+// The loop body can then modify the storage without deadlocking, and sees the entries as they were when the loop started.
+func lockedSnapshot[Key, Value any](mutex *sync.RWMutex, seq iter.Seq2[Key, Value]) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		var keys []Key
+		var values []Value
+
+		mutex.RLock()
+		for key, value := range seq {
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+		mutex.RUnlock()
+
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// lockedChunkSize is the number of entries copied at once by lockedChunks.
+const lockedChunkSize = 256
+
+// lockedChunks iterates through the entries of next, copying up to lockedChunkSize of them while holding the read lock,
+// and yielding them once it is released. Each chunk resumes through next after the last key yielded, or from the start if it is nil.
+// The loop body can then modify the storage without deadlocking, and loops stopping early only copy the chunks they reach.
+func lockedChunks[Key, Value any](mutex *sync.RWMutex, next func(after *Key) iter.Seq2[Key, Value]) iter.Seq2[Key, Value] {
+	return func(yield func(Key, Value) bool) {
+		keys := make([]Key, 0, lockedChunkSize)
+		values := make([]Value, 0, lockedChunkSize)
+		var after *Key
+
+		for {
+			keys, values = keys[:0], values[:0]
+			mutex.RLock()
+			for key, value := range next(after) {
+				keys = append(keys, key)
+				values = append(values, value)
+				if len(keys) == lockedChunkSize {
+					break
+				}
+			}
+			mutex.RUnlock()
+
+			for i := range keys {
+				if !yield(keys[i], values[i]) {
+					return
+				}
+			}
+			if len(keys) < lockedChunkSize {
+				return
+			}
+			last := keys[len(keys)-1]
+			after = &last
+		}
+	}
+}
+
+// keyValidator is implemented by the key sets of the package, checking keys against their storage before they are added.
+type keyValidator[Key any] interface {
+	validateKeys(keys []Key) error
+}
+
+func (k *keySet[Key, Store]) validateKeys(keys []Key) error {
+	return validateKeys(k.store, keys)
+}
+
+// validateKeys returns an error if the storage cannot hold one of the keys.
+func validateKeys[Key, Value any](store Storage[Key, Value], keys []Key) error {
+	if validating, ok := store.(validatingStorage[Key]); ok {
+		return validating.validate(keys)
+	}
+	return nil
+}